@@ -0,0 +1,126 @@
+package codegen
+
+import (
+	"strconv"
+
+	"github.com/myuu222/myuugo/compiler/lang"
+	"github.com/myuu222/myuugo/compiler/parse"
+)
+
+// 配列や構造体の値を比較するルーチン
+// rdiとrsiに2つの実体のアドレスを受け取り、等しければ1、そうでなければ0をraxに入れて返す
+type equalityRoutineEntry struct {
+	ty        lang.Type
+	label     string
+	generated bool
+}
+
+var equalityRoutines []*equalityRoutineEntry
+
+func isComparison(kind parse.NodeKind) bool {
+	switch kind {
+	case parse.NodeEql, parse.NodeNotEql, parse.NodeLess, parse.NodeLessEql, parse.NodeGreater, parse.NodeGreaterEql:
+		return true
+	}
+	return false
+}
+
+// 型tyの値を比較するルーチンのラベルを返す
+// 同じ型のルーチンは1つだけ生成する
+func equalityRoutine(ty lang.Type) string {
+	for _, r := range equalityRoutines {
+		if lang.TypeEquals(r.ty, ty) {
+			return r.label
+		}
+	}
+	var label = ".Lequal" + strconv.Itoa(labelNumber)
+	labelNumber += 1
+	equalityRoutines = append(equalityRoutines, &equalityRoutineEntry{ty: ty, label: label})
+	return label
+}
+
+// これまでに必要になった比較のルーチンを生成する
+// 生成中に新しく必要になったルーチンも続けて生成する
+func genEqualityRoutines() {
+	for i := 0; i < len(equalityRoutines); i++ {
+		var r = equalityRoutines[i]
+		if !r.generated {
+			genEqualityRoutine(r)
+			r.generated = true
+		}
+	}
+}
+
+func genEqualityRoutine(r *equalityRoutineEntry) {
+	var ty = lang.Underlying(r.ty)
+	var notEqualLabel = r.label + "_ne"
+
+	println("%s:", r.label)
+	emit("push rbp")
+	emit("mov rbp, rsp")
+	emit("sub rsp, 32")
+	emit("mov [rbp-8], rdi")
+	emit("mov [rbp-16], rsi")
+
+	if ty.Kind == lang.TypeStruct {
+		for i, memberType := range ty.MemberTypes {
+			emit("mov rdi, [rbp-8]")
+			emit("mov rsi, [rbp-16]")
+			genCompareAt(memberType, ty.MemberOffsets[i], notEqualLabel)
+		}
+	} else {
+		// 配列の要素を先頭から順に比較する
+		var elemType = *ty.PtrTo
+		var elemSize = lang.Sizeof(elemType)
+		var loopLabel = r.label + "_loop"
+
+		emit("mov QWORD PTR [rbp-24], 0") // 添字
+		println("%s:", loopLabel)
+		emit("mov rax, [rbp-24]")
+		emit("cmp rax, %d", ty.ArraySize)
+		emit("jge %s_eq", r.label)
+		emit("imul rax, %d", elemSize)
+		emit("mov rdi, [rbp-8]")
+		emit("mov rsi, [rbp-16]")
+		emit("add rdi, rax")
+		emit("add rsi, rax")
+		genCompareAt(elemType, 0, notEqualLabel)
+		emit("add QWORD PTR [rbp-24], 1")
+		emit("jmp %s", loopLabel)
+	}
+
+	println("%s_eq:", r.label)
+	emit("mov rax, 1")
+	emit("mov rsp, rbp")
+	emit("pop rbp")
+	emit("ret")
+	println("%s:", notEqualLabel)
+	emit("mov rax, 0")
+	emit("mov rsp, rbp")
+	emit("pop rbp")
+	emit("ret")
+}
+
+// rdiとrsiが指す領域からoffsetだけずれた位置にある型tyの値を比較し、等しくなければnotEqualLabelに飛ぶ
+func genCompareAt(ty lang.Type, offset int, notEqualLabel string) {
+	if lang.Underlying(ty).Kind == lang.TypeString {
+		emit("mov rdi, [rdi+%d]", offset)
+		emit("mov rsi, [rsi+%d]", offset)
+		emit("call strcmp")
+		emit("cmp eax, 0")
+		emit("jne %s", notEqualLabel)
+		return
+	}
+	if isAggregate(ty) {
+		emit("mov rdi, [rdi+%d]", offset)
+		emit("mov rsi, [rsi+%d]", offset)
+		emit("call %s", equalityRoutine(ty))
+		emit("cmp rax, 0")
+		emit("je %s", notEqualLabel)
+		return
+	}
+	var size = lang.Sizeof(ty)
+	emit("mov %s, %s PTR [rdi+%d]", register(0, size), word(size), offset)
+	emit("cmp %s, %s PTR [rsi+%d]", register(0, size), word(size), offset)
+	emit("jne %s", notEqualLabel)
+}
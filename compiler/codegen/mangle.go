@@ -0,0 +1,103 @@
+package codegen
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// シンボル名のマングリング
+//
+// パッケージの関数や変数は、インポートパスと名前を"."でつないだ名前(example.com/geo.NewPoint など)で区別する。
+// アセンブラのシンボルに使えない文字を含むので、インポートパスと名前をそれぞれ次の規則で書き換え、
+// "go."に続けて"."でつないだものをシンボルにする。
+//   - 英数字はそのまま
+//   - "_"は"__"
+//   - それ以外のバイトは"_"と2桁の16進数
+//
+// たとえば example.com/geo.NewPoint は go.example_2ecom_2fgeo.NewPoint になる。
+// ジェネリック関数のインスタンスは Max[int] のような名前を、メソッドやクロージャは T.M や F.func1 のような名前を同じ規則で書き換える。
+// 書き換えは可逆なので、Demangleで元の名前に戻せる。
+const manglePrefix = "go."
+
+// パッケージpackageNameの名前nameを表すシンボルを返す
+func Mangle(packageName string, name string) string {
+	return manglePrefix + escapeSymbol(packageName) + "." + escapeSymbol(name)
+}
+
+func escapeSymbol(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		var c = s[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+			b.WriteByte(c)
+		case c == '_':
+			b.WriteString("__")
+		default:
+			b.WriteString("_" + strconv.FormatUint(uint64(c)+0x100, 16)[1:])
+		}
+	}
+	return b.String()
+}
+
+// escapeSymbolで書き換えた文字列を元に戻す。正しく書き換えられた文字列でなければ第2返り値が偽になる
+func unescapeSymbol(s string) (string, bool) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '_' {
+			b.WriteByte(s[i])
+			continue
+		}
+		if i+1 < len(s) && s[i+1] == '_' {
+			b.WriteByte('_')
+			i++
+			continue
+		}
+		if i+2 >= len(s) {
+			return "", false
+		}
+		c, err := strconv.ParseUint(s[i+1:i+3], 16, 8)
+		if err != nil {
+			return "", false
+		}
+		b.WriteByte(byte(c))
+		i += 2
+	}
+	return b.String(), true
+}
+
+// Mangleで作ったシンボルを「インポートパス.名前」の形に戻す
+// Mangleで作ったシンボルでなければ第2返り値が偽になる
+func Demangle(symbol string) (string, bool) {
+	if !strings.HasPrefix(symbol, manglePrefix) {
+		return "", false
+	}
+	var parts = strings.Split(strings.TrimPrefix(symbol, manglePrefix), ".")
+	if len(parts) < 2 {
+		return "", false
+	}
+	for i, part := range parts {
+		if part == "" {
+			return "", false
+		}
+		unescaped, ok := unescapeSymbol(part)
+		if !ok {
+			return "", false
+		}
+		parts[i] = unescaped
+	}
+	return strings.Join(parts, "."), true
+}
+
+var mangledSymbol = regexp.MustCompile(`\bgo\.[A-Za-z0-9_]+(\.[A-Za-z0-9_]+)+`)
+
+// 文章の中に現れるシンボルをすべて元の名前に戻す。リンカのエラーやプロファイルを読むのに使う
+func DemangleText(text string) string {
+	return mangledSymbol.ReplaceAllStringFunc(text, func(symbol string) string {
+		if name, ok := Demangle(symbol); ok {
+			return name
+		}
+		return symbol
+	})
+}
@@ -10,16 +10,46 @@ import (
 
 var depth = 0
 
+// System V ABIで整数の引数を渡すのに使うレジスタの数
+const argumentRegisterCount = 6
+
+// System V ABIでレジスタで返せる返り値の数。これを超える場合は呼び出し側が確保した領域で返す
+const returnRegisterCount = 2
+
 func getFrameSize(program *parse.Program, functionName string) int {
 	fn := program.FindFunction(functionName)
 	if fn == nil {
 		panic("関数 \"" + functionName + " は存在しません")
 	}
 	var size int = len(fn.LocalVariables) * 8
+	if returnsIndirectly(fn) {
+		size += 8 // 返り値を書き込む領域のアドレスを保存しておく
+	}
 	size = ((size + 16 - 1) / 16) * 16
 	return size
 }
 
+// 返り値をレジスタではなく呼び出し側が確保した領域で受け渡すかどうか
+func returnsIndirectly(fn *lang.Function) bool {
+	return fn != nil && valueCount(fn.ReturnValueType) > returnRegisterCount
+}
+
+// 返り値を書き込む領域のアドレスを保存しておくスロットのRBPからのオフセット
+func resultAreaOffset(fn *lang.Function) int {
+	return len(fn.LocalVariables)*8 + 8
+}
+
+// 型tの値がスタック上で占める要素の数
+func valueCount(t lang.Type) int {
+	if t.Kind == lang.TypeMultiple {
+		return len(t.Components)
+	}
+	if t.Kind == lang.TypeVoid {
+		return 0
+	}
+	return 1
+}
+
 func register(nth int, byteCount int) string {
 	var regs64 = []string{"rax", "rdi", "rsi", "rdx", "rcx", "r8", "r9"}
 	var regs8 = []string{"al", "dil", "sil", "dl", "cl", "r8b", "r9b"}
@@ -33,6 +63,25 @@ func register(nth int, byteCount int) string {
 	}
 }
 
+// nth番目の整数の引数を渡すのに使うレジスタ
+func argumentRegister(nth int, byteCount int) string {
+	var regs64 = []string{"rdi", "rsi", "rdx", "rcx", "r8", "r9"}
+	var regs8 = []string{"dil", "sil", "dl", "cl", "r8b", "r9b"}
+
+	if byteCount == 8 {
+		return regs64[nth]
+	} else if byteCount == 1 {
+		return regs8[nth]
+	} else {
+		panic(strconv.Itoa(byteCount) + "Bのレジスタは存在しません")
+	}
+}
+
+// nth番目の返り値を返すのに使うレジスタ
+func returnRegister(nth int) string {
+	return []string{"rax", "rdx"}[nth]
+}
+
 func word(byteCount int) string {
 	if byteCount == 8 {
 		return "QWORD"
@@ -43,6 +92,14 @@ func word(byteCount int) string {
 	}
 }
 
+// 値の実体がヒープ上にあり、値としてはその先頭のアドレスを持ち回る型かどうか
+func isAggregate(ty lang.Type) bool {
+	if ty.Kind == lang.TypeUserDefined {
+		return isAggregate(*ty.PtrTo)
+	}
+	return ty.Kind == lang.TypeArray || ty.Kind == lang.TypeStruct
+}
+
 func entitySizeOf(ty lang.Type) int {
 	if ty.Kind == lang.TypeUserDefined {
 		return entitySizeOf(*ty.PtrTo)
@@ -81,6 +138,17 @@ func pop(fmt string, args ...interface{}) {
 	depth--
 }
 
+// スタックの上からcount個の要素の並びを逆順にする
+func reverseStack(count int) {
+	for i := 0; i < count/2; i++ {
+		j := count - i - 1
+		emit("mov rax, [rsp+%d]", 8*i)
+		emit("mov rdi, [rsp+%d]", 8*j)
+		emit("mov [rsp+%d], rdi", 8*i)
+		emit("mov [rsp+%d], rax", 8*j)
+	}
+}
+
 func call(format string, args ...interface{}) {
 	var modified = false
 	if depth%2 == 0 {
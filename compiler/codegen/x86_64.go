@@ -2,8 +2,6 @@ package codegen
 
 import (
 	"strconv"
-	"strings"
-	"unicode"
 
 	"github.com/myuu222/myuugo/compiler/lang"
 	"github.com/myuu222/myuugo/compiler/parse"
@@ -12,13 +10,26 @@ import (
 
 var labelNumber = 0
 var program *parse.Program
+var programs []*parse.Program
+var currentFunction *lang.Function // 生成中の関数
+var returnLabel string             // 生成中の関数の出口を表すラベル
+
+// 呼び出される関数を探す。Cの関数を呼び出している場合はnilを返す
+func calleeOf(node *parse.Node) *lang.Function {
+	for _, p := range programs {
+		if p.Name == node.In {
+			return p.FindFunction(node.Label)
+		}
+	}
+	return nil
+}
 
+// パッケージpackageNameで宣言された関数や変数のシンボル。packageNameが空の場合はCの関数を指す
 func getLabel(packageName string, label string) string {
-	if label == "main" || packageName == "" {
+	if packageName == "" {
 		return label
 	}
-	name := strings.Replace(strings.Replace(packageName, "/", "_", -1), ".", "_", -1)
-	return name + "_" + label
+	return Mangle(packageName, label)
 }
 
 // raxに文字列の長さが入っている状態からスタートする
@@ -35,85 +46,313 @@ func fromBuffer() {
 }
 
 func declare(node *parse.Node) {
+	if node.Kind == parse.NodeBlank {
+		return
+	}
 	var variable = node.Variable
 
 	if variable.Kind == lang.VariableTopLevel {
+		// 0以外のゼロ値を持つ変数はパッケージの初期化時に埋める
 		println(".data")
 		println(getLabel(node.In, variable.Name) + ":")
 
-		emit(".zero %d\n", entitySizeOf(variable.Type))
+		emit(".zero 8\n")
 		println(".text")
 		return
 	}
-	// 基本的に何もしないが配列または構造体の場合は動的にメモリを確保し、あらかじめ割り当てる
-	if variable.Type.Kind == lang.TypeArray || variable.Type.Kind == lang.TypeUserDefined {
-		genLvalue(node)
-		emit("mov rdi, %d", entitySizeOf(variable.Type))
-		call("malloc")
-		pop("rdi")
-		emit("mov [rdi], rax")
+	// ローカル変数は宣言されるたびにゼロ値で初期化する
+	genZeroValue(variable.Type)
+	pop("rax")
+	emit("mov [rbp-%d], rax", variable.Offset)
+}
+
+// 型tyのゼロ値をスタックに積む
+// 配列や構造体は中身をゼロ値で埋めた実体を新しく確保し、文字列は空文字列を指す
+func genZeroValue(ty lang.Type) {
+	var entityType = lang.Underlying(ty)
+	if entityType.Kind == lang.TypeString {
+		emit("mov rax, OFFSET FLAT:.LEmpty")
+		push("rax")
+		return
+	}
+	if !isAggregate(ty) {
+		push("0")
 		return
 	}
+
+	emit("mov rdi, 1")
+	emit("mov rsi, %d", entitySizeOf(ty))
+	call("calloc")
+	push("rax")
+
+	if entityType.Kind == lang.TypeStruct {
+		for i, memberType := range entityType.MemberTypes {
+			if !hasNonZeroZeroValue(memberType) {
+				continue
+			}
+			genZeroValue(memberType)
+			pop("rdi")
+			emit("mov rax, [rsp]")
+			emit("mov [rax+%d], rdi", entityType.MemberOffsets[i])
+		}
+		return
+	}
+	if hasNonZeroZeroValue(*entityType.PtrTo) {
+		// 配列の要素を1つずつ埋める
+		var beginLabel = ".Lzero" + strconv.Itoa(labelNumber)
+		var endLabel = ".Lzeroend" + strconv.Itoa(labelNumber)
+		labelNumber += 1
+
+		push("0") // 添字
+		println("%s:", beginLabel)
+		emit("mov rax, [rsp]")
+		emit("cmp rax, %d", entityType.ArraySize)
+		emit("jge %s", endLabel)
+		genZeroValue(*entityType.PtrTo)
+		pop("rax")
+		emit("mov rdx, [rsp]")
+		emit("mov rdi, [rsp+8]")
+		emit("mov [rdi+rdx*8], rax")
+		emit("add QWORD PTR [rsp], 1")
+		emit("jmp %s", beginLabel)
+		println("%s:", endLabel)
+		pop("rax")
+	}
+}
+
+// スタックの一番上の値を新しく確保した領域に移し、その領域のアドレスに置き換える
+func moveToHeap() {
+	emit("mov rdi, 1")
+	emit("mov rsi, 8")
+	call("calloc")
+	pop("rdi")
+	emit("mov [rax], rdi")
+	push("rax")
+}
+
+// ゼロ値がすべてのビットが0の値では表せない型かどうか
+func hasNonZeroZeroValue(ty lang.Type) bool {
+	return isAggregate(ty) || lang.Underlying(ty).Kind == lang.TypeString
+}
+
+// スタックの上からnth番目にあるスライスがnilの場合は、要素数0のスライスに置き換える
+func allocateIfNil(nth int) {
+	var label = ".Lnonnil" + strconv.Itoa(labelNumber)
+	labelNumber += 1
+
+	emit("cmp QWORD PTR [rsp+%d], 0", 8*nth)
+	emit("jne %s", label)
+	emit("mov rdi, 1")
+	emit("mov rsi, 8")
+	call("calloc")
+	emit("mov [rsp+%d], rax", 8*nth)
+	println("%s:", label)
 }
 
 func assign(lhs *parse.Node, rhs *parse.Node) {
-	if lhs.ExprType.Kind == lang.TypeArray {
-		gen(lhs)
-		gen(rhs)
+	assignTuple([]*parse.Node{lhs}, []*parse.Node{rhs})
+}
 
+// スタックに積まれたcount個の返り値を呼び出し元に返せる場所に移す
+// レジスタに収まらない場合は、呼び出し側が確保した領域に前から順に書き込み、そのアドレスをraxで返す
+func popReturnValues(count int) {
+	if count <= returnRegisterCount {
+		for i := 0; i < count; i++ {
+			pop(returnRegister(count - i - 1))
+		}
+		return
+	}
+	emit("mov rax, [rbp-%d]", resultAreaOffset(currentFunction))
+	for i := count - 1; i >= 0; i-- {
 		pop("rdi")
-		pop("rax")
+		emit("mov [rax+%d], rdi", 8*i)
+	}
+}
 
-		var size = lang.Sizeof(*lhs.ExprType.PtrTo)
+// スタックの一番上にある値を複製したものに置き換える
+// 配列や構造体の場合は実体を新しく確保してコピーする。中に含まれる配列や構造体も同様にコピーする
+func copyValue(ty lang.Type) {
+	if !isAggregate(ty) {
+		return
+	}
+	var size = entitySizeOf(ty)
 
-		for i := 0; i < lhs.ExprType.ArraySize; i++ {
-			emit("mov r10, %s PTR [rdi+%d]", word(size), size*i)
-			emit("mov %s PTR [rax+%d], r10", word(size), size*i)
+	emit("mov rdi, %d", size)
+	call("malloc")
+	pop("rsi")
+	emit("mov rdi, rax")
+	emit("mov rdx, %d", size)
+	call("memcpy") // raxにコピー先のアドレスが入る
+	push("rax")
+
+	entityType := ty
+	for entityType.Kind == lang.TypeUserDefined {
+		entityType = *entityType.PtrTo
+	}
+	if entityType.Kind == lang.TypeStruct {
+		for i, memberType := range entityType.MemberTypes {
+			if !isAggregate(memberType) {
+				continue
+			}
+			emit("mov rax, [rsp]")
+			push("QWORD PTR [rax+%d]", entityType.MemberOffsets[i])
+			copyValue(memberType)
+			pop("rdi")
+			emit("mov rax, [rsp]")
+			emit("mov [rax+%d], rdi", entityType.MemberOffsets[i])
 		}
 		return
 	}
-	if lhs.ExprType.Kind == lang.TypeStruct {
-		gen(lhs)
-		gen(rhs)
+	if isAggregate(*entityType.PtrTo) {
+		// 配列の要素を1つずつコピーする
+		var beginLabel = ".Lcopy" + strconv.Itoa(labelNumber)
+		var endLabel = ".Lcopyend" + strconv.Itoa(labelNumber)
+		labelNumber += 1
 
-		pop("rdi")
+		push("0") // 添字
+		println("%s:", beginLabel)
+		emit("mov rax, [rsp]")
+		emit("cmp rax, %d", entityType.ArraySize)
+		emit("jge %s", endLabel)
+		emit("mov rdi, [rsp+8]")
+		push("QWORD PTR [rdi+rax*8]")
+		copyValue(*entityType.PtrTo)
+		pop("rax")
+		emit("mov rdx, [rsp]")
+		emit("mov rdi, [rsp+8]")
+		emit("mov [rdi+rdx*8], rax")
+		emit("add QWORD PTR [rsp], 1")
+		emit("jmp %s", beginLabel)
+		println("%s:", endLabel)
 		pop("rax")
+	}
+}
 
-		entityType := *lhs.ExprType.PtrTo
-		offset := 0
+// 左辺の各要素に右辺の値をまとめて代入する
+// 左辺の添字やポインタの参照先と右辺の式をすべて評価し終えてから、左から順に代入する
+// 右辺が1つで左辺が複数の場合は、多値を返す関数の返り値を分解して代入する
+func assignTuple(lhss []*parse.Node, rhss []*parse.Node) {
+	for _, l := range lhss {
+		if l.Kind == parse.NodeBlank {
+			continue
+		}
+		if isAggregate(l.ExprType) {
+			// 配列や構造体は実体の中身を書き換える
+			gen(l)
+		} else {
+			genLvalue(l)
+		}
+	}
+	for i, r := range rhss {
+		gen(r)
+		if len(rhss) == len(lhss) {
+			// 代入の途中で右辺の実体が書き換わることがあるので、複製しておく
+			copyValue(lhss[i].ExprType)
+		}
+	}
 
-		for i := 0; i < len(entityType.MemberOffsets); i++ {
-			ty := entityType.MemberTypes[i]
-			emit("mov r10, %s PTR [rdi+%d]", word(lang.Sizeof(ty)), offset)
-			emit("mov %s PTR [rax+%d], r10", word(lang.Sizeof(ty)), offset)
-			offset += entityType.MemberOffsets[i]
+	// スタックは [左辺のアドレス..., 右辺の値...] となっている
+	var addressCount = 0
+	for _, l := range lhss {
+		if l.Kind != parse.NodeBlank {
+			addressCount++
 		}
-		return
 	}
+	var nth = 0 // 何番目のアドレスか
+	for i, l := range lhss {
+		if l.Kind == parse.NodeBlank {
+			continue
+		}
+		emit("mov rdi, [rsp+%d]", 8*(len(lhss)+addressCount-1-nth))
+		emit("mov rsi, [rsp+%d]", 8*(len(lhss)-1-i))
+		nth++
+		if isAggregate(l.ExprType) {
+			emit("mov rdx, %d", entitySizeOf(l.ExprType))
+			call("memcpy")
+			continue
+		}
+		var size = lang.Sizeof(l.ExprType)
+		emit("mov %s PTR [rdi], %s", word(size), register(2, size))
+	}
+	for i := 0; i < len(lhss)+addressCount; i++ {
+		pop("rax")
+	}
+}
 
-	genLvalue(lhs)
-	gen(rhs)
+// スタックに積まれた [スライス, 値] を、値を末尾に追加したスライスに置き換える
+func appendValue(elemType lang.Type) {
+	var size = lang.Sizeof(elemType)
+	allocateIfNil(1)
 
-	pop("rdi")
-	pop("rax")
+	pop("rax") // 追加する要素の値
+	pop("rdi") // スライス
+	push("rax")
 
-	emit("mov [rax], " + register(1, lang.Sizeof(lhs.ExprType)))
-}
+	emit("add QWORD PTR [rdi], 1") // 要素数を増やす
+	emit("mov rsi, [rdi]")
+	emit("imul rsi, %d", size)
+	emit("add rsi, 8") // 要素数分のアドレス
 
-// 多値を返す関数の返り値を左辺にある複数の変数に代入する
-func assignMultiple(lhss []*parse.Node, rhs *parse.Node) {
-	gen(rhs)
+	call("realloc") // 8 + 要素数 x 要素サイズ分のメモリを確保
 
-	// 分解する
-	// 右端の変数から代入されることになる
-	for i := len(lhss) - 1; i >= 0; i-- {
-		var l = lhss[i]
-		genLvalue(l)
+	emit("mov r10, rax") // 退避
 
-		pop("rax")
-		pop("rdi")
-		emit("mov [rax], " + register(1, lang.Sizeof(l.ExprType)))
-	}
+	emit("mov rdi, [rax]") // rdiに要素数を代入
+	emit("sub rdi, 1")
+	emit("imul rdi, %d", size)
+	emit("add rdi, 8")   // 要素数用のオフセットを加算
+	emit("add rax, rdi") // 代入するべき要素のアドレス
+
+	pop("rdi") // 追加する要素の値
+	emit("mov %s PTR [rax], %s", word(size), register(1, size))
+	push("r10")
+}
+
+// スタックに積まれた [スライス, スライス] を、2つを連結した新しいスライスに置き換える
+func appendSlice(elemType lang.Type) {
+	var size = lang.Sizeof(elemType)
+	allocateIfNil(0)
+	allocateIfNil(1)
+
+	pop("rsi") // 追加するスライス
+	pop("rdi") // 追加先のスライス
+	push("rdi")
+	push("rsi")
+
+	// 8 + (要素数の合計) x 要素サイズ分のメモリを新しく確保する
+	emit("mov rax, [rdi]")
+	emit("add rax, [rsi]")
+	emit("imul rax, %d", size)
+	emit("add rax, 8")
+	emit("mov rdi, rax")
+	call("malloc")
+
+	pop("rsi")
+	pop("rdi")
+	push("rax") // 結果となるスライス
+	push("rsi")
+
+	emit("mov rdx, [rdi]")
+	emit("add rdx, [rsi]")
+	emit("mov [rax], rdx") // 要素数
+
+	// 追加先のスライスの要素をコピー
+	emit("mov rsi, rdi")
+	emit("mov rdx, [rsi]")
+	emit("imul rdx, %d", size)
+	push("rdx")
+	emit("add rsi, 8")
+	emit("lea rdi, [rax+8]")
+	call("memcpy")
+
+	// 追加するスライスの要素をその後ろにコピー
+	pop("rdx")
+	emit("lea rdi, [rax+rdx]")
+	pop("rsi")
+	emit("mov rdx, [rsi]")
+	emit("imul rdx, %d", size)
+	emit("add rsi, 8")
+	call("memcpy")
 }
 
 func genLvalue(node *parse.Node) {
@@ -124,6 +363,9 @@ func genLvalue(node *parse.Node) {
 		emit("mov rax, OFFSET FLAT:%s", getLabel(node.In, node.Variable.Name))
 		push("rax")
 		return
+	} else if node.Kind == parse.NodePackageDot {
+		genLvalue(node.Children[0])
+		return
 	} else if node.Kind == parse.NodeLocalVariable {
 		emit("mov rax, rbp")
 		emit("sub rax, %d", node.Variable.Offset)
@@ -145,11 +387,7 @@ func genLvalue(node *parse.Node) {
 		return
 	} else if node.Kind == parse.NodeDot {
 		gen(node.Owner)
-		entityType := *node.Owner.ExprType.PtrTo
-
-		if node.Owner.ExprType.Kind == lang.TypePtr {
-			entityType = *entityType.PtrTo
-		}
+		entityType, _ := lang.StructOf(node.Owner.ExprType)
 
 		for i := 0; i < len(entityType.MemberNames); i++ {
 			if entityType.MemberNames[i] == node.MemberName {
@@ -200,22 +438,36 @@ func gen(node *parse.Node) {
 		return
 	}
 	if node.Kind == parse.NodeReturn {
-		if node.Target != nil {
-			var exprs = node.Target.Children
-			for _, e := range exprs {
-				gen(e)
+		fn := program.FindFunction(node.Env.FunctionName)
+		if node.Target == nil {
+			if fn.ReturnValueType.Kind == lang.TypeVoid {
+				emit("mov rax, 0")
 			}
+			// 名前付きの返り値はreturnLabelの先で読み出される
+			emit("jmp %s", returnLabel)
+			return
+		}
 
-			for i := range exprs {
-				pop("" + register(len(exprs)-i-1, 8))
+		var count = 0
+		for _, e := range node.Target.Children {
+			gen(e)
+			if e.ExprType.Kind != lang.TypeMultiple {
+				// 配列や構造体は値として返す
+				copyValue(e.ExprType)
+			}
+			count += valueCount(e.ExprType)
+		}
+		if len(fn.ResultVariables) > 0 {
+			// 名前付きの返り値に代入してから出口に向かう
+			for i := len(fn.ResultVariables) - 1; i >= 0; i-- {
+				v := fn.ResultVariables[i]
+				pop("rdi")
+				emit("mov [rbp-%d], %s", v.Offset, register(1, lang.Sizeof(v.Type)))
 			}
 		} else {
-			// void型
-			emit("mov rax, 0")
+			popReturnValues(count)
 		}
-		emit("mov rsp, rbp")
-		pop("rbp")
-		emit("ret")
+		emit("jmp %s", returnLabel)
 		return
 	}
 	if node.Kind == parse.NodeLocalVariable {
@@ -231,10 +483,6 @@ func gen(node *parse.Node) {
 	}
 	if node.Kind == parse.NodeTopLevelVariable {
 		genLvalue(node)
-
-		if node.ExprType.Kind == lang.TypeArray {
-			return
-		}
 		pop("rax")
 		if lang.Sizeof(node.ExprType) == 1 {
 			emit("movzx rax, BYTE PTR [rax]")
@@ -248,18 +496,13 @@ func gen(node *parse.Node) {
 		var lhs = node.Children[0]
 		var rhs = node.Children[1]
 
-		if rhs.ExprType.Kind == lang.TypeMultiple && len(rhs.Children) == 1 {
-			assignMultiple(lhs.Children, rhs.Children[0])
-			return
-		}
-
-		for i, l := range lhs.Children {
-			r := rhs.Children[i]
-			assign(l, r)
-		}
+		assignTuple(lhs.Children, rhs.Children)
 		return
 	}
 	if node.Kind == parse.NodeMetaIf {
+		if node.Init != nil {
+			gen(node.Init)
+		}
 		var endLabel = ".Lend" + strconv.Itoa(labelNumber)
 		var elseLabel = ".Lelse" + strconv.Itoa(labelNumber)
 
@@ -312,31 +555,80 @@ func gen(node *parse.Node) {
 		println("%s:", endLabel)
 		return
 	}
-	if node.Kind == parse.NodeRuneCall {
+	if node.Kind == parse.NodeConversion {
 		gen(node.Arguments[0])
+		if lang.Sizeof(node.ExprType) == 1 && lang.Sizeof(node.Arguments[0].ExprType) == 8 {
+			// 下位1バイトだけを残す
+			pop("rax")
+			emit("movzx rax, al")
+			push("rax")
+		}
 		return
 	}
 	if node.Kind == parse.NodeFunctionCall {
-		// TODO: rune型と配列型の扱いについて考える
+		fn := calleeOf(node)
+		var resultCount = 1
+		if fn != nil {
+			resultCount = valueCount(fn.ReturnValueType)
+		}
+
+		// 返り値がレジスタに収まらない場合は、返り値を受け取る領域をスタック上に確保し、
+		// そのアドレスを隠れた第1引数としてrdiで渡す
+		var firstRegister = 0
+		if returnsIndirectly(fn) {
+			emit("sub rsp, %d", 8*resultCount)
+			depth += resultCount
+			firstRegister = 1
+		}
+
+		// レジスタに収まらない引数はスタックに積んで渡す
+		// call命令の時点でrspが16の倍数になるように、引数を積む前に調整しておく
+		var registerCount = argumentRegisterCount - firstRegister
+		var stackCount = 0
+		if len(node.Arguments) > registerCount {
+			stackCount = len(node.Arguments) - registerCount
+		}
+		var padding = 0
+		if (depth+stackCount)%2 == 0 {
+			emit("sub rsp, 8")
+			depth++
+			padding = 1
+		}
+
+		// 引数は左から順に評価する
 		for _, argument := range node.Arguments {
 			gen(argument)
 		}
+		// 最初の引数がスタックの一番上に来るように並べ替え、前からレジスタに移していく
+		// 残りはそのままスタック渡しの引数になる
+		reverseStack(len(node.Arguments))
 		for i := range node.Arguments {
-			// 配列や構造体は先頭のアドレスだけ渡しておいてNodeFunctionDef側でうまいこと代入してもらう
-			pop("" + register(len(node.Arguments)-i, 8))
+			if i >= registerCount {
+				break
+			}
+			// 配列や構造体は先頭のアドレスだけ渡しておいてNodeFunctionDef側で複製してもらう
+			pop(argumentRegister(firstRegister+i, 8))
+		}
+		if returnsIndirectly(fn) {
+			emit("lea rdi, [rsp+%d]", 8*(stackCount+padding))
 		}
 		emit("mov al, 0") // 可変長引数の関数を呼び出すためのルール
 
 		call(getLabel(node.In, node.Label))
 
-		// 今見ている関数が多値だった場合は、rax, rdi, rsi, ...から取り出していく
-		fn := program.FindFunction(node.Label)
+		if stackCount+padding > 0 {
+			emit("add rsp, %d", 8*(stackCount+padding))
+			depth -= stackCount + padding
+		}
 
-		if fn != nil && fn.ReturnValueType.Kind == lang.TypeMultiple {
-			// raxから順にスタックに突っ込んでいく
-			for i := range fn.ReturnValueType.Components {
-				push("" + register(i, 8))
-			}
+		if returnsIndirectly(fn) {
+			// 確保した領域には前から順に返り値が書き込まれているので、最後の返り値が一番上に来るように並べ替える
+			reverseStack(resultCount)
+			return
+		}
+		if resultCount == 2 {
+			push("rax")
+			push("rdx")
 			return
 		}
 		push("rax")
@@ -350,20 +642,58 @@ func gen(node *parse.Node) {
 		push("rbp")
 		emit("mov rbp, rsp")
 
-		emit("sub rsp, %d", getFrameSize(program, node.Label))
+		var frameSize = getFrameSize(program, node.Label)
+		emit("sub rsp, %d", frameSize)
+		for offset := 8; offset <= frameSize; offset += 8 {
+			emit("mov QWORD PTR [rbp-%d], 0", offset)
+		}
 
+		currentFunction = program.FindFunction(node.Label)
+		var firstRegister = 0
+		if returnsIndirectly(currentFunction) {
+			// 返り値を書き込む領域のアドレスを退避しておく
+			emit("mov [rbp-%d], rdi", resultAreaOffset(currentFunction))
+			firstRegister = 1
+		}
 		for i, param := range node.Parameters { // 引数
-			genLvalue(param)
-			pop("rax")
-
-			emit("mov [rax], " + register(i+1, lang.Sizeof(param.ExprType)))
+			var size = lang.Sizeof(param.ExprType)
+			if firstRegister+i < argumentRegisterCount {
+				emit("mov [rbp-%d], %s", param.Variable.Offset, argumentRegister(firstRegister+i, size))
+			} else {
+				// スタック渡しの引数はリターンアドレスと退避したrbpの上に並んでいる
+				var stackIndex = firstRegister + i - argumentRegisterCount
+				emit("mov rax, [rbp+%d]", 16+8*stackIndex)
+				emit("mov [rbp-%d], %s", param.Variable.Offset, register(0, size))
+			}
+			if isAggregate(param.ExprType) {
+				// 配列や構造体は値渡しなので、受け取ったものを複製しておく
+				push("QWORD PTR [rbp-%d]", param.Variable.Offset)
+				copyValue(param.ExprType)
+				pop("rax")
+				emit("mov [rbp-%d], rax", param.Variable.Offset)
+			}
 		}
+		for _, result := range node.Results { // 名前付きの返り値はゼロ値で初期化しておく
+			declare(result)
+		}
+
+		returnLabel = ".Lreturn" + strconv.Itoa(labelNumber)
+		labelNumber += 1
 
 		gen(node.Body) // 関数本体
 
-		// エピローグ
-		// 関数の返り値の型が void 型だと仮定する
+		// 関数の出口はここに一本化されている
+		// 本体の末尾まで到達した場合は返り値の型が void 型だと仮定する
 		emit("mov rax, 0")
+		println("%s:", returnLabel)
+		if len(node.Results) > 0 {
+			for _, result := range node.Results {
+				gen(result)
+			}
+			popReturnValues(len(node.Results))
+		}
+
+		// エピローグ
 		emit("mov rsp, rbp")
 		pop("rbp")
 		emit("ret")
@@ -378,15 +708,25 @@ func gen(node *parse.Node) {
 		return
 	}
 	if node.Kind == parse.NodeAddr {
-		if node.Target.ExprType.Kind == lang.TypeUserDefined && node.Target.ExprType.PtrTo.Kind == lang.TypeStruct {
+		if lang.Underlying(node.Target.ExprType).Kind == lang.TypeStruct {
+			// 構造体へのポインタは実体のアドレスで表す
 			gen(node.Target)
 			return
 		}
+		if node.Target.Kind == parse.NodeSliceLiteral || node.Target.Kind == parse.NodeArrayLiteral {
+			gen(node.Target)
+			moveToHeap()
+			return
+		}
 		genLvalue(node.Target)
 		return
 	}
 	if node.Kind == parse.NodeDeref {
 		gen(node.Target)
+		if lang.Underlying(node.ExprType).Kind == lang.TypeStruct {
+			// 構造体へのポインタは実体のアドレスそのもの
+			return
+		}
 		pop("rax")
 		emit("mov rax, [rax]")
 		push("rax")
@@ -397,18 +737,12 @@ func gen(node *parse.Node) {
 		var rhs = node.Children[1]
 
 		for _, v := range lhs.Children {
-			declare(v)
-		}
-
-		if rhs.ExprType.Kind == lang.TypeMultiple && len(rhs.Children) == 1 {
-			assignMultiple(lhs.Children, rhs.Children[0])
-			return
+			if !v.Redeclared {
+				declare(v)
+			}
 		}
 
-		for i, l := range lhs.Children {
-			r := rhs.Children[i]
-			assign(l, r)
-		}
+		assignTuple(lhs.Children, rhs.Children)
 		return
 	}
 	if node.Kind == parse.NodeLocalVarStmt {
@@ -419,6 +753,12 @@ func gen(node *parse.Node) {
 		return
 	}
 	if node.Kind == parse.NodeTopLevelVarStmt {
+		if node.Children[0].Kind == parse.NodeExprList {
+			for _, v := range node.Children[0].Children {
+				declare(v)
+			}
+			return
+		}
 		declare(node.Children[0])
 		return
 	}
@@ -510,37 +850,68 @@ func gen(node *parse.Node) {
 
 		return
 	}
+	if node.Kind == parse.NodeNil {
+		push("0")
+		return
+	}
+	if node.Kind == parse.NodeNewCall {
+		genZeroValue(node.LiteralType)
+		if lang.Underlying(node.LiteralType).Kind != lang.TypeStruct {
+			moveToHeap()
+		}
+		return
+	}
 	if node.Kind == parse.NodeStructLiteral {
-		var entityType = *node.LiteralType.PtrTo
+		var entityType = lang.Underlying(node.LiteralType)
 
-		emit("mov rdi, %d", entitySizeOf(entityType))
-		call("malloc")
-		push("rax")
+		// 指定されなかったメンバーはゼロ値になる
+		genZeroValue(node.LiteralType)
 
 		for i := 0; i < len(node.MemberNames); i++ {
 			name := node.MemberNames[i]
-			gen(node.MemberValues[i])
 
 			offset := 0
-			memberSize := 0
+			memberType := lang.Type{}
 			for j := 0; j < len(entityType.MemberNames); j++ {
 				if entityType.MemberNames[j] == name {
 					offset = entityType.MemberOffsets[j]
-					memberSize = lang.Sizeof(entityType.MemberTypes[j])
+					memberType = entityType.MemberTypes[j]
 					break
 				}
 			}
+			memberSize := lang.Sizeof(memberType)
+
+			// 配列や構造体は、元の値と実体を共有しないようにコピーしてから格納する
+			gen(node.MemberValues[i])
+			copyValue(memberType)
 
 			pop("rdi")
 			pop("rax")
 
-			emit("mov %s PTR [rax+%d], rdi", word(memberSize), offset)
+			emit("mov %s PTR [rax+%d], %s", word(memberSize), offset, register(1, memberSize))
+			push("rax")
+		}
+		return
+	}
+	if node.Kind == parse.NodeArrayLiteral {
+		var elemType = *lang.Underlying(node.LiteralType).PtrTo
+		var elemSize = lang.Sizeof(elemType)
+
+		// 指定されなかった要素はゼロ値になる
+		genZeroValue(node.LiteralType)
+
+		for i := 0; i < len(node.Children); i++ {
+			gen(node.Children[i])
+			copyValue(elemType)
+			pop("rdi")
+			pop("rax")
+			emit("mov %s PTR [rax+%d], %s", word(elemSize), i*elemSize, register(1, elemSize))
 			push("rax")
 		}
 		return
 	}
 	if node.Kind == parse.NodeSliceLiteral {
-		var elemType = *node.LiteralType.PtrTo
+		var elemType = *lang.Underlying(node.LiteralType).PtrTo
 
 		emit("mov rdi, %d", 8+lang.Sizeof(elemType)*len(node.Children))
 		call("malloc")
@@ -549,6 +920,7 @@ func gen(node *parse.Node) {
 
 		for i := 0; i < len(node.Children); i++ {
 			gen(node.Children[i])
+			copyValue(elemType)
 			pop("rdi")
 			pop("rax")
 			emit("mov %s PTR [rax+%d], %s", word(lang.Sizeof(elemType)), 8+i*lang.Sizeof(elemType), register(1, lang.Sizeof(elemType)))
@@ -557,31 +929,19 @@ func gen(node *parse.Node) {
 		return
 	}
 	if node.Kind == parse.NodeAppendCall {
-		gen(node.Arguments[1])
-		gen(node.Arguments[0])
-
-		var elemType = node.Arguments[1].ExprType
-
-		pop("rdi")
-		emit("add QWORD PTR [rdi], 1") // 要素数を増やす
-		emit("mov rsi, [rdi]")
-		emit("imul rsi, %d", lang.Sizeof(elemType))
-		emit("add rsi, 8") // 要素数分のアドレス
+		var elemType = *node.Arguments[0].ExprType.PtrTo
 
-		call("realloc") // 8 + 要素数 x 要素サイズ分のメモリを確保
-
-		emit("mov r10, rax") // 退避
-
-		emit("mov rdi, [rax]") // rdiに要素数を代入
-		emit("sub rdi, 1")
-		emit("imul rdi, %d", lang.Sizeof(elemType))
-		emit("add rdi, 8")   // 要素数用のオフセットを加算
-		emit("add rax, rdi") // 代入するべき要素のアドレス
-
-		pop("rdi") // 追加する要素の値
-		size := lang.Sizeof(elemType)
-		emit("mov %s PTR [rax], %s", word(size), register(1, size))
-		push("r10")
+		gen(node.Arguments[0])
+		if node.HasEllipsis {
+			gen(node.Arguments[1])
+			appendSlice(elemType)
+			return
+		}
+		for _, arg := range node.Arguments[1:] {
+			gen(arg)
+			copyValue(elemType)
+			appendValue(elemType)
+		}
 		return
 	}
 	if node.Kind == parse.NodeStringCall {
@@ -605,7 +965,13 @@ func gen(node *parse.Node) {
 			return
 		}
 		if argType.Kind == lang.TypeSlice {
+			// nilスライスの要素数は0
+			var label = ".Llen" + strconv.Itoa(labelNumber)
+			labelNumber += 1
+			emit("cmp rax, 0")
+			emit("je %s", label)
 			emit("mov rax, [rax]")
+			println("%s:", label)
 			push("rax")
 			return
 		}
@@ -624,6 +990,27 @@ func gen(node *parse.Node) {
 	pop("rdi")
 	pop("rax")
 
+	if isComparison(node.Kind) {
+		var operandType = lang.Underlying(node.Lhs.ExprType)
+		if node.Lhs.ExprType.Untyped {
+			operandType = lang.Underlying(node.Rhs.ExprType)
+		}
+		if operandType.Kind == lang.TypeString {
+			// strcmpの結果と0を比較する
+			emit("mov rsi, rdi")
+			emit("mov rdi, rax")
+			call("strcmp")
+			emit("movsxd rax, eax")
+			emit("mov rdi, 0")
+		} else if isAggregate(operandType) {
+			// 等しければ1、そうでなければ0になるので、その結果と1を比較する
+			emit("mov rsi, rdi")
+			emit("mov rdi, rax")
+			call(equalityRoutine(operandType))
+			emit("mov rdi, 1")
+		}
+	}
+
 	switch node.Kind {
 	case parse.NodeAdd:
 		if node.Lhs.ExprType.Kind == lang.TypeString {
@@ -679,18 +1066,12 @@ func gen(node *parse.Node) {
 	push("rax")
 }
 
-func GenX86_64(programs []*parse.Program) {
-	program = programs[0]
+func GenX86_64(ps []*parse.Program) {
+	programs = ps
+	equalityRoutines = nil
 
 	// アセンブリの前半部分
 	println(".intel_syntax noprefix")
-
-	for _, fn := range program.Functions {
-		if fn.IsDefined && (fn.Label == "main" || unicode.IsUpper(util.RuneAt(fn.Label, 0))) {
-			println(".globl %s", getLabel(program.Name, fn.Label))
-		}
-	}
-
 	println(".data")
 
 	println(".LBuffer:")
@@ -699,7 +1080,38 @@ func GenX86_64(programs []*parse.Program) {
 	emit("  .string \"%s\"", "%d")
 	println(".LFmtSS:")
 	emit("  .string \"%s\"", "%s%s")
+	println(".LEmpty:")
+	emit("  .string \"\"")
+
+	// 指定されたパッケージと、そこから直接または間接にインポートされているすべてのパッケージのコードを生成する
+	for _, p := range programs {
+		genProgram(p)
+	}
 
+	program = programs[0]
+	if main := program.FindFunction("main"); main != nil && main.IsDefined {
+		genEntryPoint()
+	}
+}
+
+// パッケージ1つ分のコードを生成する
+func genProgram(p *parse.Program) {
+	program = p
+
+	for _, fn := range program.Functions {
+		if fn.IsDefined && (fn.Label == "main" || util.IsExported(fn.Label)) {
+			println(".globl %s", getLabel(program.Name, fn.Label))
+		}
+	}
+	for _, v := range program.TopLevelVariables {
+		if util.IsExported(v.Name) {
+			println(".globl %s", getLabel(program.Name, v.Name))
+		}
+	}
+	// 公開されていない関数や変数はローカルなシンボルのままにしておく
+	println(".globl %s", getLabel(program.Name, "init"))
+
+	println(".data")
 	for _, str := range program.StringLiterals {
 		println(str.Label + ":")
 		emit(".string %s", str.Value)
@@ -713,4 +1125,71 @@ func GenX86_64(programs []*parse.Program) {
 		}
 	}
 
+	genInit()
+	genEqualityRoutines()
+}
+
+// パッケージ変数を初期化し、init関数を宣言された順に呼び出す関数を生成する
+func genInit() {
+	depth = 0
+	println("%s:", getLabel(program.Name, "init"))
+	push("rbp")
+	emit("mov rbp, rsp")
+
+	// 初期化式より前に、すべての変数をゼロ値で埋めておく
+	for _, s := range program.Sources {
+		for _, node := range s.Code {
+			if node.Kind != parse.NodeTopLevelVarStmt {
+				continue
+			}
+			var vars = []*parse.Node{node.Children[0]}
+			if node.Children[0].Kind == parse.NodeExprList {
+				vars = node.Children[0].Children
+			}
+			for _, v := range vars {
+				if v.Kind == parse.NodeBlank || !hasNonZeroZeroValue(v.Variable.Type) {
+					continue
+				}
+				genZeroValue(v.Variable.Type)
+				genLvalue(v)
+				pop("rdi")
+				pop("rax")
+				emit("mov [rdi], rax")
+			}
+		}
+	}
+	for _, node := range program.InitOrder {
+		if node.Children[0].Kind == parse.NodeExprList {
+			assignTuple(node.Children[0].Children, node.Children[1:])
+			continue
+		}
+		assign(node.Children[0], node.Children[1])
+	}
+	for _, label := range program.InitFunctions {
+		call(getLabel(program.Name, label))
+	}
+
+	emit("mov rsp, rbp")
+	pop("rbp")
+	emit("ret")
+}
+
+// Cのmain関数として呼び出される入り口を生成する
+// インポートされているパッケージから順に初期化してからmain.mainを呼び出す
+func genEntryPoint() {
+	depth = 0
+	println(".globl main")
+	println("main:")
+	push("rbp")
+	emit("mov rbp, rsp")
+
+	for _, p := range parse.DependencyOrder(programs) {
+		call(getLabel(p.Name, "init"))
+	}
+	call(getLabel(program.Name, "main"))
+
+	emit("mov rax, 0")
+	emit("mov rsp, rbp")
+	pop("rbp")
+	emit("ret")
 }
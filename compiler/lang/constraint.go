@@ -0,0 +1,106 @@
+package lang
+
+import "strings"
+
+// 型パラメータの制約
+// TermSetsのどの集合についても、そのいずれかの項に当てはまる型だけが制約を満たす
+type Constraint struct {
+	Comparable bool // comparableを含んでいるかどうか
+	TermSets   [][]ConstraintTerm
+}
+
+type ConstraintTerm struct {
+	Type  Type
+	Tilde bool // ~Tのように、元になる型がTであればよいかどうか
+}
+
+// どんな型でも満たす制約を作る
+func NewConstraint() *Constraint {
+	return &Constraint{TermSets: [][]ConstraintTerm{}}
+}
+
+// interface { ... } の中に並べられた制約のように、両方を満たすことを要求する
+func (c *Constraint) Merge(other *Constraint) {
+	c.Comparable = c.Comparable || other.Comparable
+	c.TermSets = append(c.TermSets, other.TermSets...)
+}
+
+func (c *Constraint) SatisfiedBy(t Type) bool {
+	if c.Comparable && !IsComparable(t) {
+		return false
+	}
+	if len(c.TermSets) == 0 {
+		return true
+	}
+	if t.Kind == TypeParameter {
+		// 型パラメータは、その制約が並べているすべての型が制約を満たす場合にだけ満たす
+		var terms = t.Constraint.typeSet()
+		if len(terms) == 0 {
+			return false
+		}
+		for _, term := range terms {
+			if !c.includes(term) {
+				return false
+			}
+		}
+		return true
+	}
+	return c.includes(ConstraintTerm{Type: t})
+}
+
+// 項termに当てはまる型が、すべて制約の並べている型に含まれるかどうか
+func (c *Constraint) includes(term ConstraintTerm) bool {
+	for _, terms := range c.TermSets {
+		var ok = false
+		for _, candidate := range terms {
+			if candidate.Tilde && TypeEquals(Underlying(term.Type), candidate.Type) || !candidate.Tilde && !term.Tilde && TypeEquals(term.Type, candidate.Type) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// 制約が並べている型の項のうち、すべての集合に含まれるものを返す。型を並べていなければ空になる
+func (c *Constraint) typeSet() []ConstraintTerm {
+	if len(c.TermSets) == 0 {
+		return []ConstraintTerm{}
+	}
+	var terms = []ConstraintTerm{}
+	for _, term := range c.TermSets[0] {
+		if c.includes(term) {
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
+
+// エラーメッセージで使う制約の表記
+func (c *Constraint) String() string {
+	var elements = []string{}
+	if c.Comparable {
+		elements = append(elements, "comparable")
+	}
+	for _, terms := range c.TermSets {
+		var union = []string{}
+		for _, term := range terms {
+			if term.Tilde {
+				union = append(union, "~"+term.Type.String())
+			} else {
+				union = append(union, term.Type.String())
+			}
+		}
+		elements = append(elements, strings.Join(union, " | "))
+	}
+	if len(elements) == 0 {
+		return "any"
+	}
+	if len(elements) == 1 {
+		return elements[0]
+	}
+	return "interface{" + strings.Join(elements, "; ") + "}"
+}
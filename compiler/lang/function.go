@@ -5,7 +5,9 @@ type Function struct {
 	ParameterTypes  []Type
 	ReturnValueType Type
 	LocalVariables  []*Variable
+	ResultVariables []*Variable // 名前付きの返り値。LocalVariablesにも含まれる
 	IsDefined       bool
+	IsVariadic      bool // 最後の引数が ...T で宣言されているかどうか
 }
 
 func NewFunction(label string, parameterTypes []Type, returnValueType Type) *Function {
@@ -1,5 +1,12 @@
 package lang
 
+import (
+	"strconv"
+	"strings"
+
+	"github.com/myuu222/myuugo/compiler/util"
+)
+
 type TypeKind string
 
 const (
@@ -16,6 +23,8 @@ const (
 	TypeUndefined   TypeKind = "[TYPE] UNDEFINED"    // まだ型を決めることができていない
 	TypeUserDefined TypeKind = "[TYPE] USER DEFINED" // typeによりユーザが定義した型
 	TypeStruct      TypeKind = "[TYPE] USER STRUCT"
+	TypeNil         TypeKind = "[TYPE] NIL"            // 型のないnilだけが持つ
+	TypeParameter   TypeKind = "[TYPE] TYPE PARAMETER" // 型引数が決まる前の型パラメータ
 )
 
 type Type struct {
@@ -24,6 +33,15 @@ type Type struct {
 	ArraySize   int
 	Components  []Type
 	DefinedName string
+	Package     string // 名前の付いた型を宣言したパッケージ。構造体の型の場合は、その型を書いたパッケージ
+	Untyped     bool   // 型が決まっていない定数の場合はtrue。その場合のKindは既定の型を表す
+	Scope       string // 関数の中で宣言された型の場合に、宣言した関数の名前とその関数の中での通し番号(f.1など)
+
+	Constraint *Constraint // 型パラメータの場合の制約
+
+	// 型パラメータを持つ型をインスタンス化した型の場合にのみ使う
+	Origin        string // インスタンス化する前の型の名前
+	TypeArguments []Type
 
 	MemberNames   []string
 	MemberTypes   []Type
@@ -34,6 +52,11 @@ func NewType(kind TypeKind) Type {
 	return Type{Kind: kind}
 }
 
+// 型のない定数の型を作る
+func NewUntypedType(kind TypeKind) Type {
+	return Type{Kind: kind, Untyped: true}
+}
+
 func NewMultipleType(components []Type) Type {
 	return Type{Kind: TypeMultiple, Components: components}
 }
@@ -46,6 +69,10 @@ func NewUserDefinedType(name string, entity Type) Type {
 	return Type{Kind: TypeUserDefined, DefinedName: name, PtrTo: &entity}
 }
 
+func NewTypeParameter(name string, c *Constraint) Type {
+	return Type{Kind: TypeParameter, DefinedName: name, Constraint: c}
+}
+
 func NewPointerType(to *Type) Type {
 	return Type{Kind: TypePtr, PtrTo: to}
 }
@@ -81,29 +108,34 @@ func Sizeof(ty Type) int {
 	return 0
 }
 
+// 2つの型が同一かどうか
+// ユーザ定義の型は同じ定義から作られたものだけが同一となる
 func TypeEquals(t1 Type, t2 Type) bool {
 	if t1.Kind != t2.Kind {
 		return false
 	}
-	if t1.Kind == TypePtr {
+	if t1.Kind == TypeUserDefined {
+		return t1.PtrTo == t2.PtrTo
+	}
+	if t1.Kind == TypeParameter {
+		return t1.DefinedName == t2.DefinedName
+	}
+	if t1.Kind == TypePtr || t1.Kind == TypeSlice {
 		return TypeEquals(*t1.PtrTo, *t2.PtrTo)
 	}
 	if t1.Kind == TypeArray {
 		return t1.ArraySize == t2.ArraySize && TypeEquals(*t1.PtrTo, *t2.PtrTo)
 	}
-	if t1.Kind == TypeUserDefined {
-		return TypeEquals(*t1.PtrTo, *t2.PtrTo)
-	}
 	if t1.Kind == TypeStruct {
 		if len(t1.MemberNames) != len(t2.MemberNames) {
 			return false
 		}
 		for i := 0; i < len(t1.MemberNames); i++ {
-			var ok = false
-			for j := 0; j < len(t1.MemberNames); j++ {
-				ok = ok || (TypeEquals(t1.MemberTypes[i], t2.MemberTypes[j]) && t1.MemberNames[i] == t2.MemberNames[j])
+			if t1.MemberNames[i] != t2.MemberNames[i] || !TypeEquals(t1.MemberTypes[i], t2.MemberTypes[i]) {
+				return false
 			}
-			if !ok {
+			if !util.IsExported(t1.MemberNames[i]) && t1.Package != t2.Package {
+				// 小文字で始まるメンバーは、別のパッケージで書かれたものとは区別する
 				return false
 			}
 		}
@@ -127,6 +159,203 @@ func IsKindOfNumber(t Type) bool {
 	return t.Kind == TypeInt || t.Kind == TypeRune
 }
 
-func TypeCompatable(t1 Type, t2 Type) bool {
-	return (IsKindOfNumber(t1) && IsKindOfNumber(t2)) || TypeEquals(t1, t2)
+// ユーザ定義の型をたどって、元になっている型を返す
+func Underlying(t Type) Type {
+	for t.Kind == TypeUserDefined {
+		t = *t.PtrTo
+	}
+	return t
+}
+
+// 型tの元になる型がpredを満たすかどうか
+// 型パラメータの場合は、制約が並べている型のすべてについて、その元になる型がpredを満たすかどうか
+// 制約が型を並べていない型パラメータは、どんな型にもなりうるので満たさない
+func AllUnderlying(t Type, pred func(Type) bool) bool {
+	if t.Kind != TypeParameter {
+		return pred(Underlying(t))
+	}
+	var terms = t.Constraint.typeSet()
+	if len(terms) == 0 {
+		return false
+	}
+	for _, term := range terms {
+		if !pred(Underlying(term.Type)) {
+			return false
+		}
+	}
+	return true
+}
+
+// ==で比較できる型かどうか。スライスはnilとしか比較できないので含めない
+func IsComparable(t Type) bool {
+	if t.Kind == TypeParameter {
+		return t.Constraint.Comparable || AllUnderlying(t, IsComparable)
+	}
+	var u = Underlying(t)
+	switch u.Kind {
+	case TypeInt, TypeRune, TypeBool, TypeString, TypePtr, TypeNil:
+		return true
+	case TypeArray:
+		return IsComparable(*u.PtrTo)
+	case TypeStruct:
+		for _, memberType := range u.MemberTypes {
+			if !IsComparable(memberType) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// x.memberのようにメンバーを参照される値の型から、メンバーを持つ構造体の型を返す
+// 構造体へのポインタの場合は、指している先の構造体の型を返す
+func StructOf(t Type) (Type, bool) {
+	var u = Underlying(t)
+	if u.Kind == TypePtr {
+		u = Underlying(*u.PtrTo)
+	}
+	return u, u.Kind == TypeStruct
+}
+
+// 名前を持つ型かどうか。ユーザ定義の型、型パラメータと組み込みの型が該当する
+func IsNamed(t Type) bool {
+	return t.Kind == TypeUserDefined || t.Kind == TypeParameter || t.Kind == TypeInt || t.Kind == TypeRune || t.Kind == TypeBool || t.Kind == TypeString
+}
+
+// 型のない定数を、型が必要な場所で使うときの型を返す
+func DefaultType(t Type) Type {
+	if t.Kind == TypeMultiple {
+		var components = []Type{}
+		for _, c := range t.Components {
+			components = append(components, DefaultType(c))
+		}
+		return NewMultipleType(components)
+	}
+	t.Untyped = false
+	return t
+}
+
+// 型vの値を型tの変数に代入できるかどうか
+func AssignableTo(v Type, t Type) bool {
+	if v.Kind == TypeMultiple && t.Kind == TypeMultiple {
+		if len(v.Components) != len(t.Components) {
+			return false
+		}
+		for i := range v.Components {
+			if !AssignableTo(v.Components[i], t.Components[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	if v.Untyped {
+		if t.Kind == TypeParameter {
+			// 制約が並べているどの型の変数にも代入できる必要がある
+			return AllUnderlying(t, func(u Type) bool { return AssignableTo(v, u) })
+		}
+		// 型のない定数は、同じ種類の値を元にした型であれば代入できる
+		var u = Underlying(t)
+		if v.Kind == TypeNil {
+			return u.Kind == TypePtr || u.Kind == TypeSlice
+		}
+		if IsKindOfNumber(v) {
+			return IsKindOfNumber(u)
+		}
+		return v.Kind == u.Kind
+	}
+	if TypeEquals(v, t) {
+		return true
+	}
+	// 元になる型が同じで、少なくとも一方が名前を持たない型の場合も代入できる
+	return (!IsNamed(v) || !IsNamed(t)) && TypeEquals(Underlying(v), Underlying(t))
+}
+
+// 型vの値をT(x)によって型tに変換できるかどうか
+func ConvertibleTo(v Type, t Type) bool {
+	if AssignableTo(v, t) {
+		return true
+	}
+	// 型パラメータは、制約が並べているどの型としても変換できる必要がある
+	if v.Kind == TypeParameter {
+		return AllUnderlying(v, func(u Type) bool { return ConvertibleTo(u, t) })
+	}
+	if t.Kind == TypeParameter {
+		return AllUnderlying(t, func(u Type) bool { return ConvertibleTo(v, u) })
+	}
+	var vu, tu = Underlying(v), Underlying(t)
+	if TypeEquals(vu, tu) {
+		return true
+	}
+	if vu.Kind == TypePtr && tu.Kind == TypePtr {
+		return TypeEquals(Underlying(*vu.PtrTo), Underlying(*tu.PtrTo))
+	}
+	return IsKindOfNumber(vu) && IsKindOfNumber(tu)
+}
+
+// エラーメッセージで使う型の表記
+func (t Type) String() string {
+	return t.format(false)
+}
+
+// 名前の付いた型を、宣言したパッケージのインポートパスで修飾して表した文字列
+// 別のパッケージで宣言された同じ名前の型を区別するのに使う
+func (t Type) QualifiedString() string {
+	return t.format(true)
+}
+
+func (t Type) format(qualified bool) string {
+	var prefix = ""
+	if t.Untyped {
+		prefix = "untyped "
+	}
+	switch t.Kind {
+	case TypeInt:
+		return prefix + "int"
+	case TypeRune:
+		return prefix + "rune"
+	case TypeBool:
+		return prefix + "bool"
+	case TypeString:
+		return prefix + "string"
+	case TypeVoid:
+		return "void"
+	case TypeNil:
+		return "untyped nil"
+	case TypeUserDefined, TypeParameter:
+		if !qualified || t.Package == "" {
+			return t.DefinedName
+		}
+		if t.Origin == "" {
+			if t.Scope != "" {
+				// 別の関数で宣言された同じ名前の型と区別する
+				return t.Package + "." + t.Scope + "." + t.DefinedName
+			}
+			return t.Package + "." + t.DefinedName
+		}
+		var args = []string{}
+		for _, arg := range t.TypeArguments {
+			args = append(args, arg.format(qualified))
+		}
+		return t.Package + "." + t.Origin + "[" + strings.Join(args, ",") + "]"
+	case TypePtr:
+		return "*" + t.PtrTo.format(qualified)
+	case TypeSlice:
+		return "[]" + t.PtrTo.format(qualified)
+	case TypeArray:
+		return "[" + strconv.Itoa(t.ArraySize) + "]" + t.PtrTo.format(qualified)
+	case TypeStruct:
+		var members = []string{}
+		for i, name := range t.MemberNames {
+			members = append(members, name+" "+t.MemberTypes[i].format(qualified))
+		}
+		return "struct{" + strings.Join(members, "; ") + "}"
+	case TypeMultiple:
+		var components = []string{}
+		for _, c := range t.Components {
+			components = append(components, c.format(qualified))
+		}
+		return "(" + strings.Join(components, ", ") + ")"
+	}
+	return string(t.Kind)
 }
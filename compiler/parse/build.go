@@ -0,0 +1,196 @@
+package parse
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/myuu222/myuugo/compiler/util"
+)
+
+// コンパイラが生成するコードの対象のOSとアーキテクチャ
+const (
+	targetOS   = "linux"
+	targetArch = "amd64"
+)
+
+// go1.Nのタグを満たす最大のN。ジェネリクスを使えるバージョンまでを満たすものとして扱う
+const maxGoMinorVersion = 18
+
+var knownOS = []string{
+	"aix", "android", "darwin", "dragonfly", "freebsd", "hurd", "illumos", "ios", "js", "linux",
+	"nacl", "netbsd", "openbsd", "plan9", "solaris", "wasip1", "windows", "zos",
+}
+
+var knownArch = []string{
+	"386", "amd64", "amd64p32", "arm", "armbe", "arm64", "arm64be", "loong64", "mips", "mipsle",
+	"mips64", "mips64le", "mips64p32", "mips64p32le", "ppc", "ppc64", "ppc64le", "riscv", "riscv64",
+	"s390", "s390x", "sparc", "sparc64", "wasm",
+}
+
+// ビルドの条件に使うタグが満たされているかどうか
+func matchTag(tag string) bool {
+	if tag == targetOS || tag == targetArch || tag == "unix" || tag == "myuugo" {
+		return true
+	}
+	if strings.HasPrefix(tag, "go1.") {
+		minor, err := strconv.Atoi(strings.TrimPrefix(tag, "go1."))
+		return err == nil && minor <= maxGoMinorVersion
+	}
+	return false
+}
+
+// ファイル名がビルドの対象になるかどうか
+// _test.goで終わるファイル、_や.で始まるファイル、名前の末尾の_GOOSや_GOARCHが対象と合わないファイルは除く
+func matchFileName(path string) bool {
+	var name = filepath.Base(path)
+	if !strings.HasSuffix(name, ".go") || strings.HasPrefix(name, "_") || strings.HasPrefix(name, ".") {
+		return false
+	}
+	name = strings.TrimSuffix(name, ".go")
+	if strings.HasSuffix(name, "_test") {
+		return false
+	}
+	var parts = strings.Split(name, "_")
+	var n = len(parts)
+	if n >= 3 && includes(knownOS, parts[n-2]) && includes(knownArch, parts[n-1]) {
+		return parts[n-2] == targetOS && parts[n-1] == targetArch
+	}
+	if n >= 2 && includes(knownOS, parts[n-1]) {
+		return parts[n-1] == targetOS
+	}
+	if n >= 2 && includes(knownArch, parts[n-1]) {
+		return parts[n-1] == targetArch
+	}
+	return true
+}
+
+// ファイルの先頭の//go:build行の条件を満たすかどうか。//go:build行がなければ常に満たす
+// //go:build行を探すのは、package文より前にあるコメントの中だけ
+func matchBuildConstraint(path string, content string) bool {
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, "//") {
+			break
+		}
+		if strings.HasPrefix(line, "//go:build ") {
+			var e = &buildExpr{path: path, tokens: buildExprTokens(path, strings.TrimPrefix(line, "//go:build "))}
+			var result = e.or()
+			if e.pos != len(e.tokens) {
+				e.bad()
+			}
+			return result
+		}
+	}
+	return true
+}
+
+// ファイルがビルドの対象になるかどうか
+func matchFile(path string) bool {
+	return matchFileName(path) && matchBuildConstraint(path, util.ReadFile(path))
+}
+
+// 候補のファイルを、ビルドの対象になるものとならないものに分ける
+func selectFiles(paths []string) ([]string, []string) {
+	var selected, ignored = []string{}, []string{}
+	for _, path := range paths {
+		if matchFile(path) {
+			selected = append(selected, path)
+		} else {
+			ignored = append(ignored, path)
+		}
+	}
+	return selected, ignored
+}
+
+func buildExprTokens(path string, s string) []string {
+	var tokens = []string{}
+	for i := 0; i < len(s); {
+		var c = s[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case strings.HasPrefix(s[i:], "&&") || strings.HasPrefix(s[i:], "||"):
+			tokens = append(tokens, s[i:i+2])
+			i += 2
+		case c == '!' || c == '(' || c == ')':
+			tokens = append(tokens, s[i:i+1])
+			i++
+		case c == '_' || c == '.' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9':
+			var start = i
+			for i < len(s) && (s[i] == '_' || s[i] == '.' || 'a' <= s[i] && s[i] <= 'z' || 'A' <= s[i] && s[i] <= 'Z' || '0' <= s[i] && s[i] <= '9') {
+				i++
+			}
+			tokens = append(tokens, s[start:i])
+		default:
+			util.Alarm("%s: //go:buildの条件に使えない文字%cがあります", path, c)
+		}
+	}
+	return tokens
+}
+
+// //go:build行の条件式
+// or := and ("||" and)*
+// and := not ("&&" not)*
+// not := "!" not | "(" or ")" | タグ
+type buildExpr struct {
+	path   string
+	tokens []string
+	pos    int
+}
+
+func (e *buildExpr) bad() {
+	util.Alarm("%s: //go:buildの条件が正しくありません", e.path)
+}
+
+func (e *buildExpr) consume(token string) bool {
+	if e.pos < len(e.tokens) && e.tokens[e.pos] == token {
+		e.pos++
+		return true
+	}
+	return false
+}
+
+func (e *buildExpr) or() bool {
+	var result = e.and()
+	for e.consume("||") {
+		// 右辺の式も必ず読む
+		var rhs = e.and()
+		result = result || rhs
+	}
+	return result
+}
+
+func (e *buildExpr) and() bool {
+	var result = e.not()
+	for e.consume("&&") {
+		var rhs = e.not()
+		result = result && rhs
+	}
+	return result
+}
+
+func (e *buildExpr) not() bool {
+	if e.consume("!") {
+		return !e.not()
+	}
+	if e.consume("(") {
+		var result = e.or()
+		if !e.consume(")") {
+			e.bad()
+		}
+		return result
+	}
+	if e.pos >= len(e.tokens) {
+		e.bad()
+	}
+	var tag = e.tokens[e.pos]
+	if tag == "&&" || tag == "||" || tag == ")" {
+		e.bad()
+	}
+	e.pos++
+	return matchTag(tag)
+}
@@ -7,7 +7,10 @@ import (
 type Environment struct {
 	program        *Program
 	parent         *Environment
+	source         *Source // ファイルのスコープの場合に、そのファイル
 	localVariables []*lang.Variable
+	typeNames      []string
+	types          []lang.Type
 
 	FunctionName string
 }
@@ -31,7 +34,17 @@ func (e *Environment) AddLocalVar(ty lang.Type, name string) *lang.Variable {
 	if lvar != nil {
 		return lvar
 	}
-	lvar = lang.NewLocalVariable(ty, name)
+	return e.addLocalVar(ty, name)
+}
+
+// 名前で参照することのできないローカル変数を追加する
+// ブランク識別子_で宣言された引数などの値を置いておく場所として使う
+func (e *Environment) AddBlankLocalVar(ty lang.Type) *lang.Variable {
+	return e.addLocalVar(ty, "_")
+}
+
+func (e *Environment) addLocalVar(ty lang.Type, name string) *lang.Variable {
+	lvar := lang.NewLocalVariable(ty, name)
 	fn := e.program.FindFunction(e.FunctionName)
 
 	if fn == nil {
@@ -52,6 +65,32 @@ func (e *Environment) FindLocalVar(name string) *lang.Variable {
 	return nil
 }
 
+// 現在のスコープで名前を付けた型を追加する
+func (e *Environment) AddType(name string, ty lang.Type) {
+	e.typeNames = append(e.typeNames, name)
+	e.types = append(e.types, ty)
+}
+
+// 現在のスコープで宣言された型を探す
+func (e *Environment) FindLocalType(name string) (lang.Type, bool) {
+	for i, typeName := range e.typeNames {
+		if typeName == name {
+			return e.types[i], true
+		}
+	}
+	return lang.Type{}, false
+}
+
+// スコープを内側からたどって型を探し、見つからなければパッケージで定義された型を探す
+func (e *Environment) FindType(name string) (lang.Type, bool) {
+	for cur := e; cur != nil; cur = cur.parent {
+		if ty, ok := cur.FindLocalType(name); ok {
+			return ty, true
+		}
+	}
+	return e.program.FindType(name)
+}
+
 func (e *Environment) FindVar(name string) *lang.Variable {
 	var cur = e
 	for cur != nil {
@@ -63,3 +102,13 @@ func (e *Environment) FindVar(name string) *lang.Variable {
 	}
 	return e.program.FindTopLevelVariable(name)
 }
+
+// スコープが含まれているファイルを返す
+func (e *Environment) Source() *Source {
+	for cur := e; cur != nil; cur = cur.parent {
+		if cur.source != nil {
+			return cur.source
+		}
+	}
+	return nil
+}
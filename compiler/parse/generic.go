@@ -0,0 +1,271 @@
+package parse
+
+import (
+	"strings"
+
+	"github.com/myuu222/myuugo/compiler/lang"
+)
+
+// 型パラメータを持つ関数や型の定義
+// インスタンス化するたびに、型パラメータを型引数に置き換えながら定義のトークン列を読み直す
+type Generic struct {
+	Name        string
+	TypeParams  []string
+	Constraints []*lang.Constraint
+	Signature   *lang.Function // 関数の場合に、型パラメータを含んだままの引数と返り値の型を持つ
+	Definition  *Node          // 関数の場合に、型パラメータを仮の型にしたまま読んだ定義。本体の型を検査するのに使う
+	Instances   []*Instance
+
+	tokenizer *Tokenizer
+	pos       int // 定義の名前のトークンの位置
+	env       *Environment
+	source    *Source
+}
+
+type Instance struct {
+	TypeArguments []lang.Type
+	Label         string    // 関数の場合の、インスタンス化した関数の名前
+	Node          *Node     // 関数の場合の、インスタンス化した関数の定義
+	Type          lang.Type // 型の場合の、インスタンス化した型
+}
+
+func newGeneric(name string, t *Tokenizer, pos int) *Generic {
+	return &Generic{Name: name, tokenizer: t, pos: pos, env: Env, Instances: []*Instance{}}
+}
+
+func (g *Generic) findInstance(typeArgs []lang.Type) *Instance {
+	for _, inst := range g.Instances {
+		var same = true
+		for i := range typeArgs {
+			if !lang.TypeEquals(inst.TypeArguments[i], typeArgs[i]) {
+				same = false
+				break
+			}
+		}
+		if same {
+			return inst
+		}
+	}
+	return nil
+}
+
+// 定義のトークン列を読み直す
+func (g *Generic) reparse(f func()) {
+	reparseAt(g.tokenizer, g.pos, g.env, g.source, f)
+}
+
+// "[" 名前 ("," 名前)* 制約 ("," ...)* "]" を読み、現在のスコープで型パラメータの名前を型引数に結びつける
+// typeArgsがnilの場合は、型引数が決まる前の仮の型に結びつける
+func bindTypeParameters(typeArgs []lang.Type) ([]string, []*lang.Constraint) {
+	tokenizer.Expect(TokenLSBrace)
+	names, constraints := []string{}, []*lang.Constraint{}
+	pending := []string{} // 制約が決まっていない名前
+	for !tokenizer.Consume(TokenRSBrace) {
+		if len(names)+len(pending) > 0 {
+			tokenizer.Expect(TokenComma)
+		}
+		pending = append(pending, identifier())
+		if tokenizer.Test(TokenComma) {
+			// T, U any のように制約をまとめて書いている
+			continue
+		}
+		var c = constraint()
+		for _, name := range pending {
+			names = append(names, name)
+			constraints = append(constraints, c)
+		}
+		pending = []string{}
+	}
+	if len(pending) > 0 || len(names) == 0 {
+		BadToken(tokenizer.Fetch(), "型パラメータの制約が指定されていません")
+	}
+	for i, name := range names {
+		if typeArgs == nil {
+			Env.AddType(name, lang.NewTypeParameter(name, constraints[i]))
+		} else {
+			Env.AddType(name, typeArgs[i])
+		}
+	}
+	return names, constraints
+}
+
+// 型パラメータの制約を読む
+// any, comparable, 宣言された制約の名前, ~T | U のような型の和, またはそれらを並べた interface { ... } のいずれか
+func constraint() *lang.Constraint {
+	if !tokenizer.Test(TokenIdentifier) || tokenizer.Fetch().str != "interface" {
+		return constraintElement()
+	}
+	identifier()
+	tokenizer.Expect(TokenLbrace)
+	var c = lang.NewConstraint()
+	for !tokenizer.Consume(TokenRbrace) {
+		if skipEndOfLine() {
+			continue
+		}
+		c.Merge(constraintElement())
+	}
+	return c
+}
+
+func constraintElement() *lang.Constraint {
+	if tokenizer.Test(TokenIdentifier) && !tokenizer.Prefetch(1).Test(TokenVerticalLine) {
+		var name = tokenizer.Fetch().str
+		if name == "any" {
+			identifier()
+			return lang.NewConstraint()
+		}
+		if name == "comparable" {
+			identifier()
+			var c = lang.NewConstraint()
+			c.Comparable = true
+			return c
+		}
+		if named, ok := Env.program.Constraints[name]; ok {
+			identifier()
+			var c = lang.NewConstraint()
+			c.Merge(named)
+			return c
+		}
+	}
+	var terms = []lang.ConstraintTerm{constraintTerm()}
+	for tokenizer.Consume(TokenVerticalLine) {
+		terms = append(terms, constraintTerm())
+	}
+	var c = lang.NewConstraint()
+	c.TermSets = append(c.TermSets, terms)
+	return c
+}
+
+func constraintTerm() lang.ConstraintTerm {
+	var tilde = tokenizer.Consume(TokenTilde)
+	return lang.ConstraintTerm{Type: type_(), Tilde: tilde}
+}
+
+// 型パラメータを持つ関数の定義を読む
+// 型パラメータを仮の型にしたまま読んで引数と返り値の型を調べておき、
+// 実際に呼び出されるコードはインスタンス化するときに作る
+func genericFuncDefinition(token Token, ident string) *Node {
+	var g = Env.program.FindGenericFunction(ident)
+	g.source = source
+
+	stepIn()
+	g.TypeParams, g.Constraints = bindTypeParameters(nil)
+	g.Definition = funcSignatureAndBody(token, ident)
+	g.Signature = Env.program.FindFunction(ident)
+	g.Signature.IsDefined = false
+	stepOut()
+
+	return NewLeafNode(NodeStatementFunctionDeclaration)
+}
+
+// 型引数typeArgsで関数をインスタンス化する
+// 同じ型引数でインスタンス化済みの場合はそれを返し、新しく作った場合は第2返り値が真になる
+func InstantiateFunction(g *Generic, typeArgs []lang.Type) (*Instance, bool) {
+	if inst := g.findInstance(typeArgs); inst != nil {
+		return inst, false
+	}
+	var inst = &Instance{TypeArguments: typeArgs, Label: instanceLabel(g.Name, typeArgs)}
+	g.Instances = append(g.Instances, inst)
+
+	g.reparse(func() {
+		var token = tokenizer.Fetch()
+		identifier()
+		stepIn()
+		bindTypeParameters(typeArgs)
+		inst.Node = funcSignatureAndBody(token, inst.Label)
+		stepOut()
+	})
+	g.source.Code = append(g.source.Code, inst.Node)
+	return inst, true
+}
+
+// "[" 型 ("," 型)* "]" の形をした型引数のリストを読む
+func typeArgumentList() []lang.Type {
+	tokenizer.Expect(TokenLSBrace)
+	var typeArgs = []lang.Type{type_()}
+	for tokenizer.Consume(TokenComma) {
+		typeArgs = append(typeArgs, type_())
+	}
+	tokenizer.Expect(TokenRSBrace)
+	return typeArgs
+}
+
+// 型パラメータを持つ型を、tokenの位置に書かれた型引数typeArgsでインスタンス化する
+func instantiateType(token Token, g *Generic, typeArgs []lang.Type) lang.Type {
+	if inst := g.findInstance(typeArgs); inst != nil {
+		return inst.Type
+	}
+	var args = []string{}
+	for _, ty := range typeArgs {
+		args = append(args, ty.String())
+	}
+	var udt = lang.NewUserDefinedType(g.Name+"["+strings.Join(args, ",")+"]", lang.NewUndefinedType())
+	udt.Origin = g.Name
+	udt.Package = g.env.program.Name
+	udt.TypeArguments = typeArgs
+	// 自分自身を参照している定義を読むときのために、中身を埋める前に登録しておく
+	g.Instances = append(g.Instances, &Instance{TypeArguments: typeArgs, Type: udt})
+
+	g.reparse(func() {
+		identifier()
+		stepIn()
+		var names, constraints = bindTypeParameters(typeArgs)
+		if len(names) != len(typeArgs) {
+			BadToken(token, "型"+g.Name+"の型引数の数が正しくありません")
+		}
+		for i := range typeArgs {
+			var name, c, ty = names[i], constraints[i], typeArgs[i]
+			whenTypesComplete(func() {
+				if !ContainsTypeParameter(ty) && !c.SatisfiedBy(ty) {
+					BadToken(token, "型"+ty.String()+"は型パラメータ"+name+"の制約"+c.String()+"を満たしていません")
+				}
+			})
+		}
+		*udt.PtrTo = type_()
+		stepOut()
+	})
+	whenTypesComplete(func() {
+		checkTypeCycle(udt, []*lang.Type{})
+		layoutType(udt.PtrTo)
+	})
+	return udt
+}
+
+// 型パラメータを持つ型のインスタンスtyを、型引数をtypeArgsに替えてインスタンス化し直す
+func ReinstantiateType(ty lang.Type, typeArgs []lang.Type) lang.Type {
+	var g = findProgram(ty.Package).FindGenericType(ty.Origin)
+	return instantiateType(g.tokenizer.tokens[g.pos], g, typeArgs)
+}
+
+// インスタンス化する前の型パラメータを含んでいる型かどうか
+func ContainsTypeParameter(ty lang.Type) bool {
+	switch ty.Kind {
+	case lang.TypeParameter:
+		return true
+	case lang.TypePtr, lang.TypeSlice, lang.TypeArray:
+		return ContainsTypeParameter(*ty.PtrTo)
+	case lang.TypeUserDefined:
+		for _, arg := range ty.TypeArguments {
+			if ContainsTypeParameter(arg) {
+				return true
+			}
+		}
+	case lang.TypeStruct:
+		for _, memberType := range ty.MemberTypes {
+			if ContainsTypeParameter(memberType) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// インスタンス化した関数のラベル。Max[int] のように型引数を並べる
+// 別のパッケージの同じ名前の型で作ったインスタンスと区別できるように、型引数はインポートパスで修飾する
+func instanceLabel(name string, typeArgs []lang.Type) string {
+	var args = []string{}
+	for _, ty := range typeArgs {
+		args = append(args, ty.QualifiedString())
+	}
+	return name + "[" + strings.Join(args, ",") + "]"
+}
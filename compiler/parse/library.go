@@ -0,0 +1,69 @@
+package parse
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/myuu222/myuugo/compiler/util"
+)
+
+// 標準ライブラリのソース。インポートパスがそのままディレクトリのパスになる
+// SetLibraryDirかSetLibraryFSで変更できる
+var libraryFS fs.FS = os.DirFS("library")
+
+// 標準ライブラリのファイルを、エラーメッセージなどで表すときのパスの前置き
+var libraryName = "library"
+
+// 標準ライブラリがディスク上のディレクトリにある場合、そのディレクトリ。埋め込まれたソースを使う場合は空文字列
+var libraryDir = "library"
+
+// ディスク上のディレクトリdirを標準ライブラリとして使う
+func SetLibraryDir(dir string) {
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		util.Alarm("標準ライブラリのディレクトリ%sが見つかりません", dir)
+	}
+	libraryFS, libraryName, libraryDir = os.DirFS(dir), dir, dir
+}
+
+// fsysを標準ライブラリとして使う。nameはエラーメッセージなどでファイルを表すときのパスの前置きになる
+func SetLibraryFS(fsys fs.FS, name string) {
+	libraryFS, libraryName, libraryDir = fsys, name, ""
+}
+
+// 埋め込まれた標準ライブラリのファイルを表すパスの前置き
+// ディスク上のファイルと取り違えないように、ディスク上のパスにはならない形にする
+const embeddedPathPrefix = "embed:"
+
+// インポートパスが標準ライブラリのパッケージを指していれば、そのGoのファイルのパスを返す
+// 埋め込まれたソースの場合は、util.ReadFileで読めるようにファイルの内容を"embed:"で始まるパスで登録しておく
+func libraryFiles(importPath string) ([]string, bool) {
+	if !fs.ValidPath(importPath) {
+		return nil, false
+	}
+	entries, err := fs.ReadDir(libraryFS, importPath)
+	if err != nil {
+		return nil, false
+	}
+	var paths = []string{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".go") {
+			continue
+		}
+		var name = path.Join(importPath, entry.Name())
+		if libraryDir != "" {
+			paths = append(paths, filepath.Join(libraryDir, filepath.FromSlash(name)))
+			continue
+		}
+		content, err := fs.ReadFile(libraryFS, name)
+		if err != nil {
+			util.Alarm("標準ライブラリのファイル%sの読み取りに失敗しました", name)
+		}
+		var display = embeddedPathPrefix + path.Join(libraryName, name)
+		util.AddVirtualFile(display, string(content))
+		paths = append(paths, display)
+	}
+	return paths, len(paths) > 0
+}
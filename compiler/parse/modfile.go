@@ -0,0 +1,168 @@
+package parse
+
+import (
+	"strings"
+
+	"github.com/myuu222/myuugo/compiler/util"
+)
+
+// go.modの内容
+type ModFile struct {
+	Module    string     // module行に書かれたモジュールのパス
+	GoVersion string     // go行に書かれたバージョン
+	Requires  []*Require // require行
+	Replaces  []*Replace // replace行
+}
+
+type Require struct {
+	Path    string
+	Version string
+}
+
+// Old(とOldVersion)のモジュールの代わりにNew(とNewVersion)を使う
+// NewVersionが空の場合、Newはローカルのディレクトリを表す
+type Replace struct {
+	Old        string
+	OldVersion string
+	New        string
+	NewVersion string
+}
+
+// go.modを読んで、書かれているディレクティブを返す
+// exclude, retract, toolchainなどコンパイルに関係しないディレクティブは読み飛ばす
+func parseModFile(path string) *ModFile {
+	var mod = &ModFile{Requires: []*Require{}, Replaces: []*Replace{}}
+	var block = "" // 括弧でまとめられたブロックの中であれば、そのディレクティブの名前
+
+	for i, line := range strings.Split(util.ReadFile(path), "\n") {
+		var fields, ok = modFields(line)
+		if !ok {
+			util.Alarm("%s:%d: 閉じていない文字列があります", path, i+1)
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		var verb string
+		var args []string
+		if block != "" {
+			if len(fields) == 1 && fields[0] == ")" {
+				block = ""
+				continue
+			}
+			verb, args = block, fields
+		} else {
+			verb, args = fields[0], fields[1:]
+			if len(args) == 1 && args[0] == "(" {
+				block = verb
+				continue
+			}
+		}
+		if !mod.addDirective(verb, args) {
+			util.Alarm("%s:%d: %sの書き方が正しくありません", path, i+1, verb)
+		}
+	}
+	if block != "" {
+		util.Alarm("%s: %sのブロックが閉じていません", path, block)
+	}
+	if mod.Module == "" {
+		util.Alarm("%sにmodule行がありません", path)
+	}
+	return mod
+}
+
+// ディレクティブを1つ追加する。書き方が正しくなければ偽を返す
+func (mod *ModFile) addDirective(verb string, args []string) bool {
+	switch verb {
+	case "module":
+		if len(args) != 1 || mod.Module != "" {
+			return false
+		}
+		mod.Module = args[0]
+	case "go":
+		if len(args) != 1 || mod.GoVersion != "" {
+			return false
+		}
+		mod.GoVersion = args[0]
+	case "require":
+		if len(args) != 2 {
+			return false
+		}
+		mod.Requires = append(mod.Requires, &Require{Path: args[0], Version: args[1]})
+	case "replace":
+		// old [version] => new [version]
+		var arrow = -1
+		for i, arg := range args {
+			if arg == "=>" {
+				arrow = i
+			}
+		}
+		if arrow != 1 && arrow != 2 {
+			return false
+		}
+		var r = &Replace{Old: args[0]}
+		if arrow == 2 {
+			r.OldVersion = args[1]
+		}
+		switch len(args) - arrow - 1 {
+		case 1:
+			r.New = args[arrow+1]
+			if !isLocalPath(r.New) {
+				// ローカルのディレクトリでなければバージョンが必要
+				return false
+			}
+		case 2:
+			r.New, r.NewVersion = args[arrow+1], args[arrow+2]
+		default:
+			return false
+		}
+		mod.Replaces = append(mod.Replaces, r)
+	case "exclude", "retract", "toolchain", "godebug":
+	default:
+		return false
+	}
+	return true
+}
+
+// go.modの1行を空白で区切る。//から行末まではコメントとして無視し、"..."や`...`は1つの要素として扱う
+// 閉じていない文字列があれば第2返り値が偽になる
+func modFields(line string) ([]string, bool) {
+	var fields = []string{}
+	var i = 0
+	for i < len(line) {
+		var c = line[i]
+		if c == ' ' || c == '\t' || c == '\r' {
+			i++
+			continue
+		}
+		if strings.HasPrefix(line[i:], "//") {
+			break
+		}
+		if c == '"' || c == '`' {
+			var end = i + 1
+			for end < len(line) && line[end] != c {
+				if c == '"' && line[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(line) {
+				return nil, false
+			}
+			fields = append(fields, strings.Replace(line[i+1:end], "\\\"", "\"", -1))
+			i = end + 1
+			continue
+		}
+		var end = i
+		for end < len(line) && !strings.ContainsRune(" \t\r\"`", rune(line[end])) && !strings.HasPrefix(line[end:], "//") {
+			end++
+		}
+		fields = append(fields, line[i:end])
+		i = end
+	}
+	return fields, true
+}
+
+// replaceの置き換え先がローカルのディレクトリかどうか
+func isLocalPath(path string) bool {
+	return strings.HasPrefix(path, "/") || strings.HasPrefix(path, "./") || strings.HasPrefix(path, "../") || path == "." || path == ".."
+}
@@ -0,0 +1,163 @@
+package parse
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/myuu222/myuugo/compiler/util"
+)
+
+// go.modで宣言されたモジュール
+type Module struct {
+	Path     string    // モジュールのパス
+	Dir      string    // go.modが置かれているディレクトリ
+	File     *ModFile  // go.modの内容
+	Replaced []*Module // replaceでローカルのディレクトリに置き換えられたモジュール
+}
+
+// dirから親のディレクトリへとたどってgo.modを探し、見つかったモジュールを返す
+// 見つからなければnilを返す
+func findModule(dir string) *Module {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(abs, "go.mod")); err == nil {
+			return loadMainModule(abs)
+		}
+		var parent = filepath.Dir(abs)
+		if parent == abs {
+			return nil
+		}
+		abs = parent
+	}
+}
+
+// ディレクトリdirのgo.modを読む
+func loadModule(dir string) *Module {
+	var file = parseModFile(filepath.Join(dir, "go.mod"))
+	return &Module{Path: file.Module, Dir: dir, File: file, Replaced: []*Module{}}
+}
+
+// メインモジュールのgo.modを読み、ローカルのディレクトリへのreplaceで置き換えられたモジュールも読む
+// replaceはメインモジュールのものだけが使われる
+func loadMainModule(dir string) *Module {
+	var m = loadModule(dir)
+	for _, r := range m.File.Replaces {
+		if !isLocalPath(r.New) {
+			continue
+		}
+		var replacedDir = filepath.FromSlash(r.New)
+		if !filepath.IsAbs(replacedDir) {
+			replacedDir = filepath.Join(m.Dir, replacedDir)
+		}
+		if _, err := os.Stat(filepath.Join(replacedDir, "go.mod")); err != nil {
+			util.Alarm("%s: %sの置き換え先%sにgo.modがありません", filepath.Join(m.Dir, "go.mod"), r.Old, r.New)
+		}
+		var replaced = loadModule(replacedDir)
+		if replaced.Path != r.Old {
+			util.Alarm("%s: モジュール%sのパスが%sと宣言されています", filepath.Join(replacedDir, "go.mod"), r.Old, replaced.Path)
+		}
+		m.Replaced = append(m.Replaced, replaced)
+	}
+	return m
+}
+
+// インポートパスを含むモジュールのパスかどうか
+func modulePathContains(modulePath string, importPath string) bool {
+	return importPath == modulePath || strings.HasPrefix(importPath, modulePath+"/")
+}
+
+// requireされているが、ローカルのディレクトリに置き換えられていないモジュールのうち、インポートパスを含むものを返す
+func (m *Module) unresolvedRequireOf(importPath string) (string, bool) {
+	for _, r := range m.File.Requires {
+		if modulePathContains(r.Path, importPath) {
+			return r.Path, true
+		}
+	}
+	return "", false
+}
+
+// ディレクトリrootから見たdirの相対パスを/区切りで返す。rootそのものの場合は空文字列を返す
+// dirがrootの中になければ第2返り値が偽になる
+func relativePath(root string, dir string) (string, bool) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", false
+	}
+	rel, err := filepath.Rel(root, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, "../") {
+		return "", false
+	}
+	if rel == "." {
+		return "", true
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// ディレクトリdirのインポートパスを返す
+// メインモジュールとreplaceで置き換えられたモジュールのうち、dirを含むものから決める
+func (m *Module) importPathOf(dir string) (string, bool) {
+	for _, mod := range append(m.Replaced, m) {
+		rel, ok := relativePath(mod.Dir, dir)
+		if !ok {
+			continue
+		}
+		if rel == "" {
+			return mod.Path, true
+		}
+		return mod.Path + "/" + rel, true
+	}
+	return "", false
+}
+
+// インポートパスが表すパッケージのディレクトリを返す
+// インポートパスを含むモジュールが複数あれば、パスが最も長いモジュールから探す
+func (m *Module) dirOf(importPath string) (string, bool) {
+	var found *Module
+	for _, mod := range append([]*Module{m}, m.Replaced...) {
+		if modulePathContains(mod.Path, importPath) && (found == nil || len(mod.Path) > len(found.Path)) {
+			found = mod
+		}
+	}
+	if found == nil {
+		return "", false
+	}
+	if importPath == found.Path {
+		return found.Dir, true
+	}
+	return filepath.Join(found.Dir, filepath.FromSlash(strings.TrimPrefix(importPath, found.Path+"/"))), true
+}
+
+// ディレクトリdirにあるパッケージのインポートパスを返す
+// 標準ライブラリのディレクトリの中であればそこからの相対パス、モジュールの中であればモジュールのパスから始まるパスになる
+// どちらでもなければ空文字列を返す
+func importPathOf(module *Module, dir string) string {
+	if libraryRoot, err := filepath.Abs(libraryDir); err == nil && libraryDir != "" {
+		if rel, ok := relativePath(libraryRoot, dir); ok && rel != "" {
+			return rel
+		}
+	}
+	if module != nil {
+		if importPath, ok := module.importPathOf(dir); ok {
+			return importPath
+		}
+	}
+	return ""
+}
+
+// ディレクトリdirの直下にGoのファイルがあるかどうか
+func hasGoFiles(dir string) bool {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return false
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".go") {
+			return true
+		}
+	}
+	return false
+}
@@ -48,7 +48,7 @@ const (
 	NodeDot                          NodeKind = "[NODE] DOT"                            // A.B
 	NodeAppendCall                   NodeKind = "[NODE] APPEND CALL"                    // append(..., ...)
 	NodeStringCall                   NodeKind = "[NODE] STRING CALL"                    // string(...)
-	NodeRuneCall                     NodeKind = "[NODE] RUNE CALL"                      // rune(...)
+	NodeConversion                   NodeKind = "[NODE] CONVERSION"                     // T(...)
 	NodeLenCall                      NodeKind = "[NODE] LEN CALL"                       // len(...)
 	NodeSliceLiteral                 NodeKind = "[NODE] SLICE LITERAL"                  // []type{...}
 	NodeStructLiteral                NodeKind = "[NODE] STRUCT LITERAL"                 // typeName{...}
@@ -56,6 +56,11 @@ const (
 	NodeImportStmt                   NodeKind = "[NODE] IMPORT STMT"                    // import (
 	NodeStatementFunctionDeclaration NodeKind = "[NODE] STATEMENT FUNCTION DECLARATION" // 関数宣言
 	NodePackageDot                   NodeKind = "[NODE] PACKAGE DOT"
+	NodeBlank                        NodeKind = "[NODE] BLANK"             // _
+	NodeNil                          NodeKind = "[NODE] NIL"               // nil
+	NodeNewCall                      NodeKind = "[NODE] NEW CALL"          // new(T)
+	NodeCompositeLiteral             NodeKind = "[NODE] COMPOSITE LITERAL" // T{...}。意味解析で型ごとのリテラルに置き換わる
+	NodeArrayLiteral                 NodeKind = "[NODE] ARRAY LITERAL"     // [n]type{...}
 )
 
 type Node struct {
@@ -89,15 +94,32 @@ type Node struct {
 
 	// kindがNodeForの場合にのみ使う
 	// for Init; Condition; Update {}
+	// kindがNodeMetaIfの場合はInitだけを使う
+	// if Init; Condition {}
 	Init   *Node
 	Update *Node
 
 	// kindがNodeFunctionDefの場合にのみ使う
 	Parameters []*Node
+	Results    []*Node // 名前付きの返り値
 
 	// kindがNodeFunctionCallの場合にのみ使う
 	Arguments []*Node
 
+	// kindがNodeFunctionCallの場合にのみ使う
+	TypeArguments []lang.Type // F[int](x) のように明示された型引数
+
+	// kindがNodeFunctionCall, NodeDotの場合にのみ使う
+	NameToken Token // エラーを報告する位置として使う、関数名やメンバー名のトークン
+
+	// kindがNodeFunctionCall, NodeAppendCallの場合にのみ使う
+	// 最後の引数が f(xs...) のように展開されているかどうか
+	HasEllipsis bool
+
+	// kindがNodeLocalVariableの場合にのみ使う
+	// := の左辺で同じスコープの既存の変数を再宣言しているかどうか
+	Redeclared bool
+
 	// kindがNodeReturn, NodeAddr, NodeDerefの場合にのみ使う
 	Target *Node
 
@@ -105,10 +127,12 @@ type Node struct {
 	Owner      *Node
 	MemberName string
 
-	// kindがNodeSliceLiteralまたはNodeStructLiteralの場合にのみ使う
+	// kindがNodeSliceLiteral, NodeArrayLiteral, NodeStructLiteral, NodeCompositeLiteralの場合にのみ使う
+	// kindがNodeConversion, NodeNewCallの場合は変換先や確保する型、NodeNumの場合は文字リテラルかどうかを表すのに使う
 	LiteralType lang.Type
 
-	// kindがNodeStructLiteralの場合にのみ使う
+	// kindがNodeStructLiteralまたはNodeCompositeLiteralの場合にのみ使う
+	// NodeCompositeLiteralでは名前の指定されていない要素の名前は空文字列になる
 	MemberNames  []string
 	MemberValues []*Node
 
@@ -212,8 +236,8 @@ func NewSliceLiteral(ty lang.Type, elements []*Node) *Node {
 	return n
 }
 
-func NewStructLiteral(ty lang.Type, memberNames []string, memberValues []*Node) *Node {
-	n := newNodeBase(NodeStructLiteral)
+func NewCompositeLiteral(ty lang.Type, memberNames []string, memberValues []*Node) *Node {
+	n := newNodeBase(NodeCompositeLiteral)
 	n.LiteralType = ty
 	n.MemberNames = memberNames
 	n.MemberValues = memberValues
@@ -242,9 +266,10 @@ func NewImportStmtNode(packages []string) *Node {
 	return n
 }
 
-func NewAppendCallNode(arg1 *Node, arg2 *Node) *Node {
+func NewAppendCallNode(arguments []*Node, hasEllipsis bool) *Node {
 	n := newNodeBase(NodeAppendCall)
-	n.Arguments = []*Node{arg1, arg2}
+	n.Arguments = arguments
+	n.HasEllipsis = hasEllipsis
 	return n
 }
 
@@ -260,8 +285,15 @@ func NewStringCallNode(arg *Node) *Node {
 	return n
 }
 
-func NewRuneCallNode(arg *Node) *Node {
-	n := newNodeBase(NodeRuneCall)
+func NewNewCallNode(ty lang.Type) *Node {
+	n := newNodeBase(NodeNewCall)
+	n.LiteralType = ty
+	return n
+}
+
+func NewConversionNode(ty lang.Type, arg *Node) *Node {
+	n := newNodeBase(NodeConversion)
+	n.LiteralType = ty
 	n.Arguments = []*Node{arg}
 	return n
 }
@@ -1,7 +1,7 @@
 package parse
 
 import (
-	"os"
+	"strconv"
 	"strings"
 
 	"github.com/myuu222/myuugo/compiler/lang"
@@ -79,10 +79,73 @@ func isType() bool {
 	if ident == "int" || ident == "rune" || ident == "bool" || ident == "string" || ident == "struct" {
 		return true
 	}
-	_, ok := Env.program.FindType(ident)
+	if _, _, ok := qualifiedTypeName(); ok {
+		return true
+	}
+	if _, ok := Env.FindType(ident); ok || Env.program.FindGenericType(ident) != nil {
+		return true
+	}
+	_, _, ok := dotImportedTypeName(ident)
 	return ok
 }
 
+// 型名が何個のトークンで書かれているか。pkg.T の形なら3になる
+func typeNameLength() int {
+	if _, _, ok := qualifiedTypeName(); ok {
+		return 3
+	}
+	return 1
+}
+
+// 現在のトークンから始まる、インポートしたパッケージの型名 pkg.T を探す
+// 見つかった場合は、型を宣言しているパッケージとインポートを返す
+func qualifiedTypeName() (*Program, *Import, bool) {
+	var src = Env.Source()
+	if src == nil || !tokenizer.Test(TokenIdentifier) || !tokenizer.Prefetch(1).Test(TokenDot) || !tokenizer.Prefetch(2).Test(TokenIdentifier) {
+		return nil, nil, false
+	}
+	imp, ok := src.FindPackage(tokenizer.Fetch().str)
+	if !ok || isLocalVar(imp.Name) {
+		return nil, nil, false
+	}
+	var p = findProgram(imp.Path)
+	var name = tokenizer.Prefetch(2).str
+	if p == nil {
+		return nil, nil, false
+	}
+	if _, ok := p.FindType(name); ok || p.FindGenericType(name) != nil {
+		return p, imp, true
+	}
+	return nil, nil, false
+}
+
+// ドットインポートしたパッケージのうち、型名nameを公開しているものを探す
+func dotImportedTypeName(name string) (*Program, *Import, bool) {
+	var src = Env.Source()
+	if src == nil || !util.IsExported(name) {
+		return nil, nil, false
+	}
+	for _, imp := range src.DotImports() {
+		var p = findProgram(imp.Path)
+		if p == nil {
+			continue
+		}
+		if _, ok := p.FindType(name); ok || p.FindGenericType(name) != nil {
+			return p, imp, true
+		}
+	}
+	return nil, nil, false
+}
+
+// パッケージpで宣言された型nameを返す。ジェネリック型の場合は続く型引数でインスタンス化する
+func importedType(p *Program, token Token, name string) lang.Type {
+	if g := p.FindGenericType(name); g != nil {
+		return instantiateType(token, g, typeArgumentList())
+	}
+	ty, _ := p.FindType(name)
+	return ty
+}
+
 func type_() lang.Type {
 	if tokenizer.Consume(TokenStar) {
 		ty := type_()
@@ -100,6 +163,20 @@ func type_() lang.Type {
 		return lang.NewArrayType(ty, arraySize)
 	}
 
+	if p, imp, ok := qualifiedTypeName(); ok {
+		// 他のパッケージで宣言された型
+		identifier()
+		tokenizer.Expect(TokenDot)
+		imp.Used = true
+		var token = tokenizer.Fetch()
+		var name = identifier()
+		if !util.IsExported(name) {
+			BadToken(token, "cannot refer to unexported name "+imp.Name+"."+name)
+		}
+		return importedType(p, token, name)
+	}
+
+	token := tokenizer.Fetch()
 	ident := identifier()
 	if ident == "int" {
 		return lang.NewType(lang.TypeInt)
@@ -125,9 +202,23 @@ func type_() lang.Type {
 			names = append(names, name)
 			types = append(types, ty)
 		}
-		return lang.NewStructType(names, types)
+		var ty = lang.NewStructType(names, types)
+		// 小文字で始まるメンバーは、構造体の型を書いたパッケージの中からだけ参照できる
+		ty.Package = Env.program.Name
+		return ty
+	}
+	if g := Env.program.FindGenericType(ident); g != nil {
+		return instantiateType(token, g, typeArgumentList())
+	}
+	ty, ok := Env.FindType(ident)
+	if !ok {
+		if p, imp, ok := dotImportedTypeName(ident); ok {
+			// ドットインポートしたパッケージで宣言された型
+			imp.Used = true
+			return importedType(p, token, ident)
+		}
+		BadToken(token, "未定義の型です")
 	}
-	ty, _ := Env.program.FindType(ident)
 	return ty
 }
 
@@ -148,33 +239,52 @@ func stepOut() {
 
 var source *Source
 
-func forceTrailingSlash(path string) string {
-	if !strings.HasSuffix(path, "/") {
-		path = path + "/"
-	}
-	return path
-}
+// 読んでいるパッケージのインポートパス。わからなければ空文字列
+var packagePath string
+
+// パッケージを読んでいる途中かどうか
+// 読んでいる間は、まだ中身の決まっていない型があるので、型の配置や制約の検査を読み終わるまで遅らせる
+var parsingPackage = false
+var afterParse []func()
 
-// path直下にgo.modがあれば読み込んで、module名を返す。
-// なければ空文字列を返す。
-func readModuleNameIfExists(path string) string {
-	path = forceTrailingSlash(path)
-	_, err := os.Stat(path + "go.mod")
-	if err != nil {
-		return ""
+// 型の定義がすべて揃ったあとに行う処理を登録する。パッケージを読み終わっていればすぐに行う
+func whenTypesComplete(f func()) {
+	if parsingPackage {
+		afterParse = append(afterParse, f)
+	} else {
+		f()
 	}
-	content := util.ReadFile(path + "go.mod")
-	firstLine := strings.Split(content, "\n")[0]
-	return strings.Split(firstLine, " ")[0]
 }
 
-var sourcePrefix string
+// tokenizerのpos番目のトークンから読み直す
+// 読んでいる途中だったトークン列や環境は、読み終わったあとに元に戻す
+func reparseAt(t *Tokenizer, pos int, env *Environment, src *Source, f func()) {
+	var savedTokenizer, savedEnv, savedSource = tokenizer, Env, source
+	var savedPos = t.pos
+	tokenizer, Env, source = t, env, src
+	tokenizer.pos = pos
 
-func parseProgram(srcPrefix string, path string) *Program {
-	sourcePrefix = srcPrefix
+	f()
+
+	t.pos = savedPos
+	tokenizer, Env, source = savedTokenizer, savedEnv, savedSource
+}
+
+// パッケージのpackage文とimport文だけを読む
+// インポートしているパッケージを先に読めるように、残りの宣言はparseDeclarationsで読む
+// candidatesのうち、ビルドの条件に合うファイルだけを読む
+func loadPackage(importPath string, candidates []string, isLibrary bool) *Program {
+	packagePath = importPath
 
-	goFilePaths := util.EnumerateGoFilePaths(path)
 	Env = NewEnvironment()
+	Env.program.IsLibrary = isLibrary
+	Env.program.env = Env
+
+	goFilePaths, ignored := selectFiles(candidates)
+	Env.program.IgnoredFiles = ignored
+	if len(goFilePaths) == 0 && importPath != "" {
+		util.Alarm("パッケージ%sにはビルドの対象になるGoのファイルがありません", importPath)
+	}
 
 	for _, p := range goFilePaths {
 		stepIn()
@@ -182,6 +292,9 @@ func parseProgram(srcPrefix string, path string) *Program {
 		source = NewSource(p)
 		tokenizer = NewTokenizer()
 		tokenizer.Tokenize(p)
+		source.tokenizer = tokenizer
+		source.env = Env
+		Env.source = source
 
 		for skipEndOfLine() {
 		}
@@ -197,14 +310,125 @@ func parseProgram(srcPrefix string, path string) *Program {
 				break
 			}
 		}
-		source.Code = append(source.Code, topLevelStmtList().Children...)
 		Env.program.Sources = append(Env.program.Sources, source)
 		stepOut()
 	}
-
 	return Env.program
 }
 
+// loadPackageで読んだパッケージの、import文より後にある宣言を読む
+func parseDeclarations(p *Program) {
+	parsingPackage = true
+
+	// 宣言の順番やファイルによらず型を参照できるように、先にすべてのファイルから型の名前を集めておく
+	var constraintPositions = [][]int{}
+	for _, s := range p.Sources {
+		Env = s.env
+		constraintPositions = append(constraintPositions, declareTypes(s.tokenizer))
+	}
+	for i, s := range p.Sources {
+		Env = s.env
+		declareConstraints(s.tokenizer, constraintPositions[i])
+	}
+	p.resolveAliases()
+
+	for _, s := range p.Sources {
+		Env, source, tokenizer = s.env, s, s.tokenizer
+		s.Code = append(s.Code, topLevelStmtList().Children...)
+	}
+	Env = p.env
+	p.LayoutTypes()
+	p.checkImportNames()
+	for _, f := range afterParse {
+		f()
+	}
+	afterParse, parsingPackage = nil, false
+}
+
+// トップレベルのtype文で宣言されている型の名前と、型パラメータを持つ関数を登録する
+// 制約の宣言は中身に他の型を含むので、すべての型の名前が揃ってから読めるように名前の位置を返す
+func declareTypes(t *Tokenizer) []int {
+	var constraintPositions = []int{}
+	var declare = func(pos int) {
+		var name = t.tokens[pos]
+		if Env.program.isTypeNameDeclared(name.str) {
+			BadToken(name, "型"+name.str+"は既に定義されています")
+		}
+		var next = t.tokens[pos+1]
+		if next.Test(TokenEqual) {
+			Env.program.aliases = append(Env.program.aliases, &typeAlias{name: name.str, tokenizer: t, pos: pos, env: Env})
+			return
+		}
+		if next.Test(TokenIdentifier) && next.str == "interface" {
+			Env.program.Constraints[name.str] = lang.NewConstraint()
+			constraintPositions = append(constraintPositions, pos)
+			return
+		}
+		if next.Test(TokenLSBrace) && t.tokens[pos+2].Test(TokenIdentifier) {
+			Env.program.GenericTypes = append(Env.program.GenericTypes, newGeneric(name.str, t, pos))
+			return
+		}
+		Env.program.DeclareType(name.str)
+	}
+
+	var depth = 0
+	for i := 0; i < len(t.tokens); i++ {
+		var token = t.tokens[i]
+		if token.Test(TokenLbrace) || token.Test(TokenLparen) {
+			depth++
+		}
+		if token.Test(TokenRbrace) || token.Test(TokenRparen) {
+			depth--
+		}
+		if depth == 0 && token.Test(TokenFunc) && t.tokens[i+1].Test(TokenIdentifier) && t.tokens[i+2].Test(TokenLSBrace) {
+			Env.program.GenericFunctions = append(Env.program.GenericFunctions, newGeneric(t.tokens[i+1].str, t, i+1))
+			continue
+		}
+		if depth != 0 || !token.Test(TokenType) {
+			continue
+		}
+		if t.tokens[i+1].Test(TokenIdentifier) {
+			declare(i + 1)
+			continue
+		}
+		if !t.tokens[i+1].Test(TokenLparen) {
+			continue
+		}
+
+		// type ( ... ) でまとめて宣言されている場合は、各行の先頭にある名前を登録する
+		var inner = 0
+		var head = true
+		for i = i + 2; !t.tokens[i].Test(TokenEof) && (inner > 0 || !t.tokens[i].Test(TokenRparen)); i++ {
+			var tok = t.tokens[i]
+			if tok.Test(TokenLbrace) || tok.Test(TokenLparen) {
+				inner++
+			}
+			if tok.Test(TokenRbrace) || tok.Test(TokenRparen) {
+				inner--
+			}
+			if inner == 0 && (tok.Test(TokenNewLine) || tok.Test(TokenSemicolon)) {
+				head = true
+				continue
+			}
+			if inner == 0 && head && tok.Test(TokenIdentifier) {
+				declare(i)
+			}
+			head = false
+		}
+	}
+	return constraintPositions
+}
+
+// declareTypesで位置を調べておいた制約の宣言を読む
+func declareConstraints(t *Tokenizer, positions []int) {
+	for _, pos := range positions {
+		reparseAt(t, pos, Env, source, func() {
+			var name = identifier()
+			Env.program.Constraints[name].Merge(constraint())
+		})
+	}
+}
+
 func includes(slice []string, e string) bool {
 	for _, s := range slice {
 		if s == e {
@@ -237,12 +461,27 @@ func findNextPackageName(programs []*Program) (string, bool) {
 	return "", false
 }
 
-// Create an AST for all Go files directly under `path`.
-func Parse(path string) []*Program {
-	srcPrefix := readModuleNameIfExists(path)
-	programs := []*Program{parseProgram(srcPrefix, path)}
+// 読んでいるパッケージと、そこからインポートされているパッケージ
+var loadedPrograms []*Program
+
+// インポートパスからパッケージを探す
+func findProgram(path string) *Program {
+	for _, p := range loadedPrograms {
+		if p.Name == path {
+			return p
+		}
+	}
+	return nil
+}
 
-	libraryPackageNames := []string{"os", "fmt", "strconv"}
+// pathのパッケージと、そこから直接または間接にインポートされているすべてのパッケージのpackage文とimport文を読む
+// インポートされているパッケージは、標準ライブラリかモジュールの中から探す
+func Load(path string) []*Program {
+	var module = findModule(path)
+	programs := []*Program{loadPackage(importPathOf(module, path), util.EnumerateGoFilePaths(path), false)}
+	if len(programs[0].Sources) == 0 {
+		util.Alarm("%sにはビルドの対象になるGoのファイルがありません", path)
+	}
 
 	for {
 		nextPackageName, ok := findNextPackageName(programs)
@@ -250,13 +489,38 @@ func Parse(path string) []*Program {
 			break
 		}
 		// 標準ライブラリだった場合
-
-		if includes(libraryPackageNames, nextPackageName) {
-			programs = append(programs, parseProgram("", "./library/"+nextPackageName))
+		if files, ok := libraryFiles(nextPackageName); ok {
+			programs = append(programs, loadPackage(nextPackageName, files, true))
 			continue
 		}
-		// TODO: 自作パッケージだった場合
-		break
+		// 自作パッケージだった場合
+		if module != nil {
+			dir, ok := module.dirOf(nextPackageName)
+			if ok && hasGoFiles(dir) {
+				programs = append(programs, loadPackage(nextPackageName, util.EnumerateGoFilePaths(dir), false))
+				continue
+			}
+			if required, isRequired := module.unresolvedRequireOf(nextPackageName); !ok && isRequired {
+				util.Alarm("パッケージ%sを読むには、go.modでモジュール%sをローカルのディレクトリにreplaceしてください", nextPackageName, required)
+			}
+		}
+		util.Alarm("パッケージ%sが見つかりません", nextPackageName)
+	}
+	for _, p := range programs {
+		for _, s := range p.Sources {
+			s.nameImports(programs)
+		}
+	}
+	return programs
+}
+
+// Create an AST for all Go files directly under `path`.
+// 他のパッケージの型を参照できるように、インポートされているパッケージから順に宣言を読む
+func Parse(path string) []*Program {
+	var programs = Load(path)
+	loadedPrograms = programs
+	for _, p := range DependencyOrder(programs) {
+		parseDeclarations(p)
 	}
 	return programs
 }
@@ -270,27 +534,42 @@ func importStmt() *Node {
 		tokenizer.Expect(TokenNewLine)
 
 		for !tokenizer.Consume(TokenRparen) {
-			pkg := strings.Trim(stringLiteral(), "\"")
-			packages = append(packages, pkg)
+			if skipEndOfLine() {
+				// 空行
+				continue
+			}
+			packages = append(packages, importSpec())
 			tokenizer.Expect(TokenNewLine)
-
-			source.AddPackage(pkg)
 		}
 		return NewImportStmtNode(packages)
 	}
-	packages = append(packages, strings.Trim(stringLiteral(), "\""))
-	source.AddPackage(packages[0])
+	packages = append(packages, importSpec())
 	return NewImportStmtNode(packages)
 }
 
+// [名前 | "." | "_"] インポートパス を読み、ソースに記録する
+func importSpec() string {
+	var name = ""
+	if tokenizer.Consume(TokenDot) {
+		name = "."
+	} else if tokenizer.Test(TokenIdentifier) {
+		name = identifier()
+	}
+	var token = tokenizer.Fetch()
+	var pkg = strings.Trim(stringLiteral(), "\"")
+	source.AddImport(pkg, name, token)
+	return pkg
+}
+
 func packageStmt() *Node {
 	var n = NewLeafNode(NodePackageStmt)
 
 	tokenizer.Expect(TokenPackage)
-	if sourcePrefix == "" {
-		n.Label = identifier()
-	} else {
-		n.Label = sourcePrefix + "/" + identifier()
+	n.Label = identifier()
+	Env.program.PackageName = n.Label
+	if n.Label != "main" && packagePath != "" {
+		// mainパッケージ以外はインポートパスで区別する
+		n.Label = packagePath
 	}
 	Env.program.Name = n.Label
 
@@ -331,7 +610,13 @@ func topLevelStmtList() *Node {
 		if skipEndOfLine() {
 			continue
 		}
-		stmts = append(stmts, topLevelStmt())
+		stmt := topLevelStmt()
+		if stmt.Kind == NodeStmtList {
+			// var ( ... ) のようにまとめて宣言されたもの
+			stmts = append(stmts, stmt.Children...)
+		} else {
+			stmts = append(stmts, stmt)
+		}
 
 		endLineRequired = true
 		if skipEndOfLine() {
@@ -345,15 +630,73 @@ func topLevelStmtList() *Node {
 
 func typeStmt() *Node {
 	tokenizer.Expect(TokenType)
-	typeName := identifier()
-	entityType := type_()
-	definedType := lang.NewUserDefinedType(typeName, entityType)
+	return declGroup(typeSpec)
+}
 
-	Env.program.RegisterType(definedType)
+func typeSpec() *Node {
+	token := tokenizer.Fetch()
+	typeName := identifier()
+	if tokenizer.Test(TokenIdentifier) && tokenizer.Fetch().str == "interface" {
+		// 型パラメータの制約はdeclareConstraintsで登録済みなので読み飛ばす
+		if Env.FunctionName != "" {
+			BadToken(token, "関数の中で制約を宣言することはできません")
+		}
+		constraint()
+		return NewNode(NodeTypeStmt, []*Node{})
+	}
+	if tokenizer.Test(TokenLSBrace) && tokenizer.Prefetch(1).Test(TokenIdentifier) {
+		// 型パラメータを持つ型はインスタンス化するときに読み直すので、ここでは仮の型で読み飛ばす
+		if Env.FunctionName != "" {
+			BadToken(token, "関数の中で型パラメータを持つ型を宣言することはできません")
+		}
+		stepIn()
+		bindTypeParameters(nil)
+		type_()
+		stepOut()
+		return NewNode(NodeTypeStmt, []*Node{})
+	}
+	if tokenizer.Consume(TokenEqual) {
+		// 型エイリアス
+		// トップレベルのものはresolveAliasesで登録済みなので、関数の中で宣言されたものだけを登録する
+		var ty = type_()
+		if Env.FunctionName != "" {
+			declareLocalType(token, ty)
+			whenTypesComplete(func() {
+				layoutType(&ty)
+			})
+		}
+		return NewNode(NodeTypeStmt, []*Node{})
+	}
+	if Env.FunctionName != "" {
+		// 関数の中で宣言された型は、宣言されたブロックの中でだけ参照できる
+		// 自分自身を参照できるように、元になる型を読む前に登録しておく
+		var definedType = lang.NewUserDefinedType(typeName, lang.NewUndefinedType())
+		definedType.Package = Env.program.Name
+		Env.program.localTypes[Env.FunctionName]++
+		definedType.Scope = Env.FunctionName + "." + strconv.Itoa(Env.program.localTypes[Env.FunctionName])
+		declareLocalType(token, definedType)
+		*definedType.PtrTo = type_()
+		whenTypesComplete(func() {
+			checkTypeCycle(definedType, []*lang.Type{})
+			layoutType(definedType.PtrTo)
+		})
+		return NewNode(NodeTypeStmt, []*Node{})
+	}
+	// トップレベルの型の名前はdeclareTypesで登録済みなので、元になる型を埋める
+	definedType, _ := Env.program.FindType(typeName)
+	*definedType.PtrTo = type_()
 
 	return NewNode(NodeTypeStmt, []*Node{})
 }
 
+// tokenが表す名前の型を現在のスコープに宣言する
+func declareLocalType(token Token, ty lang.Type) {
+	if _, ok := Env.FindLocalType(token.str); ok {
+		BadToken(token, "型"+token.str+"は既に定義されています")
+	}
+	Env.AddType(token.str, ty)
+}
+
 func topLevelStmt() *Node {
 	// 関数定義
 	if tokenizer.Test(TokenFunc) {
@@ -375,6 +718,9 @@ func topLevelStmt() *Node {
 	if tokenizer.Test(TokenFor) {
 		BadToken(tokenizer.Fetch(), "for文はトップレベルでは使用できません")
 	}
+	if tokenizer.Test(TokenSwitch) {
+		BadToken(tokenizer.Fetch(), "switch文はトップレベルでは使用できません")
+	}
 	if tokenizer.Test(TokenReturn) {
 		BadToken(tokenizer.Fetch(), "return文はトップレベルでは使用できません")
 	}
@@ -417,10 +763,18 @@ func localStmt() *Node {
 	if tokenizer.Test(TokenFor) {
 		return forStmt()
 	}
+	// switch文
+	if tokenizer.Test(TokenSwitch) {
+		return switchStmt()
+	}
 	// var文
 	if tokenizer.Test(TokenVar) {
 		return localVarStmt()
 	}
+	// type文
+	if tokenizer.Test(TokenType) {
+		return typeStmt()
+	}
 	if tokenizer.Consume(TokenReturn) {
 		if tokenizer.Test(TokenNewLine) || tokenizer.Test(TokenSemicolon) {
 			// 空のreturn文
@@ -431,61 +785,191 @@ func localStmt() *Node {
 	return simpleStmt()
 }
 
-// トップレベル変数は初期化式は与えないことにする
 func topLevelVarStmt() *Node {
 	tokenizer.Expect(TokenVar)
-	var v = topLevelVariableDeclaration()
-	v.Variable.Type = type_()
-	return NewNode(NodeTopLevelVarStmt, []*Node{v})
+	return declGroup(topLevelVarSpec)
+}
+
+// var ( ... ) や type ( ... ) のようにまとめて書かれた宣言を読む
+// まとめて書かれていない場合は宣言を1つだけ読む
+func declGroup(spec func() *Node) *Node {
+	if !tokenizer.Consume(TokenLparen) {
+		return spec()
+	}
+	var stmts = []*Node{}
+	for !tokenizer.Consume(TokenRparen) {
+		if skipEndOfLine() {
+			continue
+		}
+		stmts = append(stmts, flatten(spec())...)
+		if !tokenizer.Test(TokenRparen) {
+			endOfLine()
+		}
+	}
+	return NewNode(NodeStmtList, stmts)
+}
+
+// 複数の文に分けて表された宣言を1つの文のリストにまとめる
+func flatten(stmt *Node) []*Node {
+	if stmt.Kind == NodeStmtList {
+		return stmt.Children
+	}
+	return []*Node{stmt}
+}
+
+// 変数ごとの宣言に分けたものを返す。1つだけの場合はその宣言を返す
+func declList(stmts []*Node) *Node {
+	if len(stmts) == 1 {
+		return stmts[0]
+	}
+	return NewNode(NodeStmtList, stmts)
+}
+
+// トップレベル変数の初期化式はパッケージの初期化時に依存関係の順に評価される
+// var a, b = 1, 2 は変数ごとの宣言に分け、var a, b = f() は1つの宣言でまとめて初期化する
+func topLevelVarSpec() *Node {
+	var token = tokenizer.Fetch()
+	var vars = []*Node{topLevelVariableDeclaration()}
+	for tokenizer.Consume(TokenComma) {
+		vars = append(vars, topLevelVariableDeclaration())
+	}
+	if !tokenizer.Test(TokenEqual) {
+		var ty = type_()
+		for _, v := range vars {
+			if v.Kind == NodeBlank {
+				v.ExprType = ty
+			} else {
+				v.Variable.Type = ty
+			}
+		}
+	}
+
+	var stmts = []*Node{}
+	if !tokenizer.Consume(TokenEqual) {
+		for _, v := range vars {
+			stmts = append(stmts, NewNode(NodeTopLevelVarStmt, []*Node{v}))
+		}
+		return declList(stmts)
+	}
+	var values = exprList().Children
+	if len(vars) > 1 && len(values) == 1 {
+		return NewBinaryNode(NodeTopLevelVarStmt, NewNode(NodeExprList, vars), values[0])
+	}
+	if len(vars) != len(values) {
+		BadToken(token, "変数の数と初期化式の数が一致しません")
+	}
+	for i, v := range vars {
+		stmts = append(stmts, NewBinaryNode(NodeTopLevelVarStmt, v, values[i]))
+	}
+	return declList(stmts)
 }
 
 func localVarStmt() *Node {
 	tokenizer.Expect(TokenVar)
-	var v = localVariableDeclaration()
+	return declGroup(localVarSpec)
+}
 
-	if !isType() {
-		// 型が明示されていないときは初期化が必須
-		tokenizer.Expect(TokenEqual)
-		return NewBinaryNode(NodeLocalVarStmt, v, expr())
+// var a, b = 1, 2 は変数ごとの宣言に分け、var a, b = f() は:=と同じようにまとめて初期化する
+func localVarSpec() *Node {
+	var tokens = []Token{}
+	for len(tokens) == 0 || tokenizer.Consume(TokenComma) {
+		tokens = append(tokens, tokenizer.Fetch())
+		identifier()
 	}
 
-	ty := type_()
-	v.Variable.Type = ty
+	var ty = lang.NewUndefinedType()
+	if isType() {
+		ty = type_()
+	} else if !tokenizer.Test(TokenEqual) {
+		// 型が明示されていないときは初期化が必須
+		BadToken(tokenizer.Fetch(), "'"+string(TokenEqual)+"'ではありません")
+	}
+	var values = []*Node{}
 	if tokenizer.Consume(TokenEqual) {
-		return NewBinaryNode(NodeLocalVarStmt, v, expr())
+		values = exprList().Children
+	}
+
+	// 変数は宣言の終わりから見えるようになるので、初期化式を読んでから宣言する
+	var vars = []*Node{}
+	for _, token := range tokens {
+		v := declareLocalVariable(token)
+		v.Variable.Type = ty
+		vars = append(vars, v)
+	}
+	if len(vars) > 1 && len(values) == 1 {
+		return NewBinaryNode(NodeShortVarDeclStmt, NewNode(NodeLocalVarList, vars), NewNode(NodeExprList, values))
+	}
+	if len(values) > 0 && len(vars) != len(values) {
+		BadToken(tokens[0], "変数の数と初期化式の数が一致しません")
+	}
+	var stmts = []*Node{}
+	for i, v := range vars {
+		if len(values) > 0 {
+			stmts = append(stmts, NewBinaryNode(NodeLocalVarStmt, v, values[i]))
+		} else {
+			stmts = append(stmts, NewNode(NodeLocalVarStmt, []*Node{v}))
+		}
 	}
-	return NewNode(NodeLocalVarStmt, []*Node{v})
+	return declList(stmts)
 }
 
 func funcDefinition() *Node {
 	tokenizer.Expect(TokenFunc)
+	token := tokenizer.Fetch()
 	ident := identifier()
+	if ident == "init" {
+		// init関数は1つのパッケージにいくつでも定義でき、名前で参照することはできない
+		ident = "init." + strconv.Itoa(len(Env.program.InitFunctions))
+		Env.program.InitFunctions = append(Env.program.InitFunctions, ident)
+	}
+	if tokenizer.Test(TokenLSBrace) {
+		return genericFuncDefinition(token, ident)
+	}
+	return funcSignatureAndBody(token, ident)
+}
 
+// 関数の名前より後ろの引数、返り値、本体を読み、identという名前の関数として登録する
+func funcSignatureAndBody(token Token, ident string) *Node {
 	stepInFunction(ident)
 	var fn = lang.NewFunction(Env.FunctionName, []lang.Type{}, lang.NewUndefinedType())
 	Env.program.RegisterFunction(fn)
 
 	var parameters = make([]*Node, 0)
-
-	tokenizer.Expect(TokenLparen)
-	for !tokenizer.Consume(TokenRparen) {
-		if len(parameters) > 0 {
-			tokenizer.Expect(TokenComma)
-		}
-		lvarNode := localVariableDeclaration()
+	names, types, variadic := parameterList()
+	for i, name := range names {
+		lvarNode := declareLocalVariable(name)
+		lvarNode.Variable.Type = types[i]
 		parameters = append(parameters, lvarNode)
-		lvarNode.Variable.Type = type_()
-		fn.ParameterTypes = append(fn.ParameterTypes, lvarNode.Variable.Type)
+	}
+	fn.ParameterTypes = types
+	fn.IsVariadic = variadic
+
+	if strings.HasPrefix(ident, "init.") && (len(names) > 0 || tokenizer.Test(TokenLparen) || isType()) {
+		BadToken(token, "init関数は引数も返り値も持つことができません")
 	}
 
+	var results = make([]*Node, 0)
 	fn.ReturnValueType = lang.NewType(lang.TypeVoid)
-	if tokenizer.Consume(TokenLparen) { // 多値
+	if tokenizer.Test(TokenLparen) && isNamedList() {
+		// 名前付きの返り値は関数の先頭でゼロ値に初期化されるローカル変数として扱う
+		names, types, variadic := parameterList()
+		if variadic {
+			BadToken(tokenizer.Fetch(), "返り値に可変長の型は指定できません")
+		}
+		for i, name := range names {
+			lvarNode := declareLocalVariable(name)
+			lvarNode.Variable.Type = types[i]
+			results = append(results, lvarNode)
+			fn.ResultVariables = append(fn.ResultVariables, lvarNode.Variable)
+		}
+		fn.ReturnValueType = resultType(types)
+	} else if tokenizer.Consume(TokenLparen) { // 多値
 		var types = []lang.Type{type_()}
 		for tokenizer.Consume(TokenComma) {
 			types = append(types, type_())
 		}
 		tokenizer.Expect(TokenRparen)
-		fn.ReturnValueType = lang.NewMultipleType(types)
+		fn.ReturnValueType = resultType(types)
 	} else if isType() {
 		fn.ReturnValueType = type_()
 	}
@@ -498,7 +982,10 @@ func funcDefinition() *Node {
 
 		tokenizer.Expect(TokenRbrace)
 		node = NewFunctionDefNode(functionName, parameters, body)
+		node.Results = results
 		fn.IsDefined = true
+	} else if strings.HasPrefix(ident, "init.") {
+		BadToken(token, "init関数には本体が必要です")
 	} else {
 		// 関数宣言
 		node = NewLeafNode(NodeStatementFunctionDeclaration)
@@ -507,6 +994,90 @@ func funcDefinition() *Node {
 	return node
 }
 
+// 返り値の型のリストから関数の返り値の型を作る
+func resultType(types []lang.Type) lang.Type {
+	if len(types) == 1 {
+		return types[0]
+	}
+	return lang.NewMultipleType(types)
+}
+
+// 括弧で囲まれた宣言のリストが (n int, err string) のように名前付きかどうかを先読みして判定する
+func isNamedList() bool {
+	var depth = 0
+	var itemHead = true
+	for pos := 1; ; pos++ {
+		tok := tokenizer.Prefetch(pos)
+		if tok.Test(TokenEof) {
+			return false
+		}
+		if tok.Test(TokenLparen) || tok.Test(TokenLSBrace) || tok.Test(TokenLbrace) {
+			depth++
+		}
+		if tok.Test(TokenRparen) || tok.Test(TokenRSBrace) || tok.Test(TokenRbrace) {
+			if depth == 0 {
+				return false
+			}
+			depth--
+		}
+		if depth == 0 && tok.Test(TokenComma) {
+			itemHead = true
+			continue
+		}
+		if itemHead && tok.Test(TokenIdentifier) && tok.str != "struct" {
+			next := tokenizer.Prefetch(pos + 1)
+			if !next.Test(TokenComma) && !next.Test(TokenRparen) && !next.Test(TokenDot) {
+				return true
+			}
+		}
+		itemHead = false
+	}
+}
+
+// "(" 名前 ("," 名前)* "..."? 型 ("," ...)* ")" の形をした宣言のリストを読み、
+// 名前のトークンとそれぞれの型、最後が可変長引数かどうかを返す
+func parameterList() ([]Token, []lang.Type, bool) {
+	tokenizer.Expect(TokenLparen)
+	names, types := []Token{}, []lang.Type{}
+	pending := []Token{} // 型が決まっていない名前
+	var variadic = false
+	for !tokenizer.Consume(TokenRparen) {
+		if len(names)+len(pending) > 0 {
+			tokenizer.Expect(TokenComma)
+		}
+		if variadic {
+			BadToken(tokenizer.Fetch(), "可変長引数は最後の引数にしか指定できません")
+		}
+		pending = append(pending, tokenizer.Fetch())
+		identifier()
+		if tokenizer.Test(TokenComma) {
+			// a, b int のように型をまとめて書いている
+			continue
+		}
+
+		var ty lang.Type
+		if tokenizer.Consume(TokenEllipsis) {
+			// 可変長引数はスライスとして受け取る
+			if len(pending) > 1 {
+				BadToken(pending[0], "可変長引数は最後の引数にしか指定できません")
+			}
+			variadic = true
+			ty = lang.NewSliceType(type_())
+		} else {
+			ty = type_()
+		}
+		for _, name := range pending {
+			names = append(names, name)
+			types = append(types, ty)
+		}
+		pending = []Token{}
+	}
+	if len(pending) > 0 {
+		BadToken(pending[len(pending)-1], "型が指定されていません")
+	}
+	return names, types, variadic
+}
+
 // range は未対応
 func forStmt() *Node {
 	stepIn()
@@ -555,18 +1126,52 @@ func metaIfStmt() *Node {
 		BadToken(token, "'"+string(TokenIf)+"'ではありません")
 	}
 
+	// 初期化文で宣言された変数は、else節を含むif文全体から見える
+	stepIn()
+	tokenizer.Expect(TokenIf)
+	var init *Node
+	if hasInitStmt() {
+		init = simpleStmt()
+		tokenizer.Expect(TokenSemicolon)
+	}
+
 	var ifNode = ifStmt()
+	var elseNode *Node
 	if tokenizer.Test(TokenElse) {
-		var elseNode = elseStmt()
-		return NewMetaIfNode(ifNode, elseNode)
+		elseNode = elseStmt()
+	}
+	var node = NewMetaIfNode(ifNode, elseNode)
+	node.Init = init
+	stepOut()
+	return node
+}
+
+// if文やswitch文の条件の前に、;で区切られた初期化文があるかどうかを先読みして判定する
+func hasInitStmt() bool {
+	var depth = 0
+	for pos := 0; ; pos++ {
+		tok := tokenizer.Prefetch(pos)
+		if tok.Test(TokenEof) || tok.Test(TokenNewLine) {
+			return false
+		}
+		if tok.Test(TokenLparen) || tok.Test(TokenLSBrace) {
+			depth++
+		}
+		if tok.Test(TokenRparen) || tok.Test(TokenRSBrace) {
+			depth--
+		}
+		if depth == 0 && tok.Test(TokenLbrace) {
+			return false
+		}
+		if depth == 0 && tok.Test(TokenSemicolon) {
+			return true
+		}
 	}
-	return NewMetaIfNode(ifNode, nil)
 }
 
 func ifStmt() *Node {
 	stepIn()
 
-	tokenizer.Expect(TokenIf)
 	var cond = expr()
 	tokenizer.Expect(TokenLbrace)
 	var body = localStmtList()
@@ -576,6 +1181,108 @@ func ifStmt() *Node {
 	return NewIfNode(cond, body)
 }
 
+// switch文はif ... else if ... else ... の連鎖に変換する
+// タグの値は一度だけ評価して名前のないローカル変数に入れておき、各case節の式と==で比較する
+func switchStmt() *Node {
+	tokenizer.Expect(TokenSwitch)
+
+	// 初期化文で宣言された変数は、switch文全体から見える
+	stepIn()
+	var inits = []*Node{}
+	if hasInitStmt() {
+		inits = append(inits, simpleStmt())
+		tokenizer.Expect(TokenSemicolon)
+	}
+
+	var tag *lang.Variable
+	if !tokenizer.Test(TokenLbrace) {
+		var value = expr()
+		tag = Env.AddLocalVar(lang.NewUndefinedType(), "")
+		var tagNode = NewLeafNode(NodeLocalVariable)
+		tagNode.Variable = tag
+		inits = append(inits, NewBinaryNode(NodeShortVarDeclStmt, NewNode(NodeLocalVarList, []*Node{tagNode}), NewNode(NodeExprList, []*Node{value})))
+	}
+
+	tokenizer.Expect(TokenLbrace)
+	var clauses = []*Node{}
+	var defaultBody *Node
+	for !tokenizer.Consume(TokenRbrace) {
+		if skipEndOfLine() {
+			continue
+		}
+		if tokenizer.Test(TokenDefault) {
+			if defaultBody != nil {
+				BadToken(tokenizer.Fetch(), "default節が複数あります")
+			}
+			tokenizer.Expect(TokenDefault)
+			tokenizer.Expect(TokenColon)
+			defaultBody = caseClauseBody()
+			continue
+		}
+
+		tokenizer.Expect(TokenCase)
+		var cond *Node
+		for {
+			var e = expr()
+			if tag != nil {
+				var tagNode = NewLeafNode(NodeLocalVariable)
+				tagNode.Variable = tag
+				e = NewBinaryOperationNode(NodeEql, tagNode, e)
+			}
+			if cond == nil {
+				cond = e
+			} else {
+				cond = NewBinaryOperationNode(NodeLogicalOr, cond, e)
+			}
+			if !tokenizer.Consume(TokenComma) {
+				break
+			}
+		}
+		tokenizer.Expect(TokenColon)
+		clauses = append(clauses, NewIfNode(cond, caseClauseBody()))
+	}
+
+	// default節はどのcase節にも当てはまらなかったときに実行されるので、連鎖の最後に置く
+	var chain *Node
+	if defaultBody != nil {
+		chain = NewElseNode(defaultBody)
+	}
+	for i := len(clauses) - 1; i >= 0; i-- {
+		chain = NewMetaIfNode(clauses[i], chain)
+	}
+
+	var node *Node
+	if chain != nil && chain.Kind == NodeMetaIf {
+		node = chain
+		node.Init = NewNode(NodeStmtList, inits)
+	} else {
+		// case節がない場合は初期化文とdefault節を順に実行するだけ
+		if chain != nil {
+			inits = append(inits, chain.Body)
+		}
+		node = NewNode(NodeStmtList, inits)
+	}
+	stepOut()
+	return node
+}
+
+// case節またはdefault節の本体を、次の節の始まりかswitch文の終わりまで読む
+func caseClauseBody() *Node {
+	stepIn()
+	var stmts = []*Node{}
+	for !tokenizer.Test(TokenCase) && !tokenizer.Test(TokenDefault) && !tokenizer.Test(TokenRbrace) {
+		if skipEndOfLine() {
+			continue
+		}
+		stmts = append(stmts, localStmt())
+		if !skipEndOfLine() && !tokenizer.Test(TokenRbrace) {
+			BadToken(tokenizer.Fetch(), "文の区切り文字が必要です")
+		}
+	}
+	stepOut()
+	return NewNode(NodeStmtList, stmts)
+}
+
 func elseStmt() *Node {
 	tokenizer.Expect(TokenElse)
 
@@ -589,10 +1296,35 @@ func elseStmt() *Node {
 	return metaIfStmt()
 }
 
+// := の左辺を読む
+// 同じスコープに宣言済みの変数は再宣言として扱うが、新しい変数が少なくとも1つは必要
 func localVarList() *Node {
-	var lvars = []*Node{localVariableDeclaration()}
-	for tokenizer.Consume(TokenComma) {
-		lvars = append(lvars, localVariableDeclaration())
+	var first = tokenizer.Fetch()
+	var lvars = []*Node{}
+	var names = map[string]bool{}
+	var hasNew = false
+	for len(lvars) == 0 || tokenizer.Consume(TokenComma) {
+		var token = tokenizer.Fetch()
+		var ident = identifier()
+		if ident != "_" && names[ident] {
+			BadToken(token, ":=の左辺に同じ変数が複数回現れています")
+		}
+		names[ident] = true
+
+		if lvar := Env.FindLocalVar(ident); ident != "_" && lvar != nil {
+			var node = NewLeafNode(NodeLocalVariable)
+			node.Variable = lvar
+			node.Redeclared = true
+			lvars = append(lvars, node)
+			continue
+		}
+		if ident != "_" {
+			hasNew = true
+		}
+		lvars = append(lvars, declareLocalVariable(token))
+	}
+	if !hasNew {
+		BadToken(first, ":=の左辺に新しい変数がありません")
 	}
 	return NewNode(NodeLocalVarList, lvars)
 }
@@ -709,27 +1441,49 @@ func unary() *Node {
 	return primary()
 }
 
-func structTypeLiteral() *Node {
-	token := tokenizer.Fetch()
-	ident := identifier()
-	ty, ok := Env.program.FindType(ident)
-	if !ok {
-		BadToken(token, "未定義の型のリテラルです")
-	}
+// "{" (element ("," element)* ","?)? "}" を読む
+// element は (identifier ":")? (expr | "{" ... "}") で、型を省略した "{" ... "}" は要素の型のリテラルになる
+func compositeLiteral(ty lang.Type) *Node {
 	names, values := []string{}, []*Node{}
 	tokenizer.Expect(TokenLbrace)
-	for !tokenizer.Consume(TokenRbrace) {
-		if len(names) > 0 {
-			tokenizer.Expect(TokenComma)
+	for {
+		skipNewLines()
+		if tokenizer.Consume(TokenRbrace) {
+			break
+		}
+		var name = ""
+		if tokenizer.Test(TokenIdentifier) && tokenizer.Prefetch(1).Test(TokenColon) {
+			name = identifier()
+			tokenizer.Expect(TokenColon)
+		}
+		names = append(names, name)
+		if tokenizer.Test(TokenLbrace) {
+			values = append(values, compositeLiteral(lang.NewUndefinedType()))
+		} else {
+			values = append(values, expr())
+		}
+		skipNewLines()
+		if !tokenizer.Consume(TokenComma) {
+			tokenizer.Expect(TokenRbrace)
+			break
 		}
-		names = append(names, identifier())
-		tokenizer.Expect(TokenColon)
-		values = append(values, expr())
 	}
-	return NewStructLiteral(ty, names, values)
+	return NewCompositeLiteral(ty, names, values)
+}
+
+func skipNewLines() {
+	for tokenizer.Consume(TokenNewLine) {
+	}
 }
 
 func primary() *Node {
+	// (*T)(...) のように、括弧で囲んだ型への変換
+	if isParenthesizedType() {
+		tokenizer.Expect(TokenLparen)
+		var ty = type_()
+		tokenizer.Expect(TokenRparen)
+		return conversion(ty)
+	}
 	// 次のトークンが "(" なら、"(" expr ")" のはず
 	if tokenizer.Consume(TokenLparen) {
 		var n = expr()
@@ -737,7 +1491,12 @@ func primary() *Node {
 		return n
 	}
 	if tokenizer.Test(TokenNumber) {
-		return NewNodeNum(numberLiteral())
+		var isRune = strings.HasPrefix(tokenizer.Fetch().str, "'")
+		var n = NewNodeNum(numberLiteral())
+		if isRune {
+			n.LiteralType = lang.NewType(lang.TypeRune)
+		}
+		return n
 	}
 	if tokenizer.Test(TokenBool) {
 		return NewNodeBool(boolLiteral())
@@ -750,50 +1509,70 @@ func primary() *Node {
 	}
 
 	if tokenizer.Test(TokenLSBrace) {
-		ty := type_()
-
-		if ty.Kind == lang.TypeSlice {
-			elements := []*Node{}
-			tokenizer.Expect(TokenLbrace)
-			for !tokenizer.Consume(TokenRbrace) {
-				if len(elements) > 0 {
-					tokenizer.Expect(TokenComma)
-				}
-				elements = append(elements, expr())
-			}
-			return NewSliceLiteral(ty, elements)
+		if tokenizer.Prefetch(1).Test(TokenEllipsis) {
+			// [...]type{...} の要素数は要素の個数で決まる
+			tokenizer.Expect(TokenLSBrace)
+			tokenizer.Expect(TokenEllipsis)
+			tokenizer.Expect(TokenRSBrace)
+			var elemType = type_()
+			var n = compositeLiteral(lang.NewArrayType(elemType, 0))
+			n.LiteralType.ArraySize = len(n.MemberValues)
+			return n
+		}
+		var ty = type_()
+		if tokenizer.Test(TokenLparen) {
+			// []T(...) のような型変換
+			return conversion(ty)
 		}
-		panic("未実装の型のリテラルです")
+		return compositeLiteral(ty)
 	}
 
 	var tok = tokenizer.Fetch()
-	ty, ok := Env.program.FindType(tok.str)
-	// struct型のリテラル
-	if ok {
-		names, values := []string{}, []*Node{}
-		type_()
-		tokenizer.Expect(TokenLbrace)
-		for !tokenizer.Consume(TokenRbrace) {
-			if len(names) > 0 {
-				tokenizer.Expect(TokenComma)
-			}
-			names = append(names, identifier())
-			tokenizer.Expect(TokenColon)
-			values = append(values, expr())
-		}
-		return NewStructLiteral(ty, names, values)
+	// 型変換
+	// string(...)は[]runeからの変換を行う組み込み関数として扱う
+	if tok.str != "string" && isType() && tokenizer.Prefetch(typeNameLength()).Test(TokenLparen) {
+		return conversion(type_())
+	}
+	// 名前の付いた型のリテラル
+	if tok.str != "string" && isType() {
+		return compositeLiteral(type_())
 	}
 
 	var pkgName = ""
-	var name = tokenizer.Fetch().str
-	pkgName, ok = source.FindPackage(name)
-
-	if ok {
+	imp, ok := source.FindPackage(tokenizer.Fetch().str)
+	if ok && tokenizer.Prefetch(1).Test(TokenDot) && !isLocalVar(imp.Name) {
 		identifier()
 		tokenizer.Expect(TokenDot)
+		pkgName = imp.Path
+		imp.Used = true
+		var token = tokenizer.Fetch()
+		var p = findProgram(pkgName)
+		if !p.Declares(token.str) {
+			BadToken(token, "undefined: "+imp.Name+"."+token.str)
+		}
+		if !util.IsExported(token.str) {
+			BadToken(token, "cannot refer to unexported name "+imp.Name+"."+token.str)
+		}
+		if tokenizer.Prefetch(1).Test(TokenLparen) && p.FindFunction(token.str) == nil && p.FindGenericFunction(token.str) == nil {
+			BadToken(token, "invalid operation: cannot call non-function "+imp.Name+"."+token.str)
+		}
+	} else {
+		ok = false
 	}
 
-	var n *Node = named()
+	var n *Node
+	if ok && !tokenizer.Prefetch(1).Test(TokenLparen) && findProgram(pkgName).FindGenericFunction(tokenizer.Fetch().str) == nil {
+		// 他のパッケージの変数。同じ名前のローカル変数があっても、そのパッケージの変数を指す
+		n = NewLeafNode(NodeTopLevelVariable)
+		n.Label = identifier()
+	} else if ok {
+		n = named(findProgram(pkgName))
+	} else {
+		n = named(Env.program)
+	}
+	if ok {
+		n.In = pkgName
+	}
 	for {
 		if tokenizer.Consume(TokenLSBrace) {
 			n = NewIndexNode(n, expr())
@@ -802,30 +1581,63 @@ func primary() *Node {
 		}
 		if tokenizer.Consume(TokenDot) {
 			// メソッド呼び出しは一旦無視
+			var token = tokenizer.Fetch()
 			n = NewDotNode(n, identifier())
+			n.NameToken = token
 			continue
 		}
 		break
 	}
 	if ok {
-		n.In = pkgName
 		n = NewNode(NodePackageDot, []*Node{n})
 		n.Label = pkgName
 	}
 	return n
 }
 
-func named() *Node {
+// 型tyへの変換の、"(" expr ")" の部分を読む
+func conversion(ty lang.Type) *Node {
+	tokenizer.Expect(TokenLparen)
+	var arg = expr()
+	tokenizer.Expect(TokenRparen)
+	return NewConversionNode(ty, arg)
+}
+
+// 現在のトークンから、(*T) のように括弧で囲んだポインタ型が始まるかどうか
+// 括弧で囲む必要があるのはポインタ型への変換だけなので、それ以外の (T) は式として読む
+func isParenthesizedType() bool {
+	if !tokenizer.Test(TokenLparen) || !tokenizer.Prefetch(1).Test(TokenStar) {
+		return false
+	}
+	var pos = tokenizer.pos
+	defer func() { tokenizer.pos = pos }()
+	tokenizer.Succ()
+	// (*p) のような式と区別するため、* の後ろが型かどうかを調べる
+	for tokenizer.Consume(TokenStar) {
+	}
+	return isType()
+}
+
+// 名前から始まる式を読む。関数はパッケージpで宣言されたものとして探す
+func named(p *Program) *Node {
+	if tokenizer.Prefetch(1).Test(TokenLSBrace) && declaresGenericFunction(p, tokenizer.Fetch().str) && (p != Env.program || Env.FindVar(tokenizer.Fetch().str) == nil) {
+		// 型引数を明示したジェネリック関数の呼び出し
+		var token = tokenizer.Fetch()
+		var functionName = identifier()
+		var typeArgs = typeArgumentList()
+		arguments, hasEllipsis := argumentList()
+		n := NewFunctionCallNode(functionName, arguments)
+		n.HasEllipsis = hasEllipsis
+		n.TypeArguments = typeArgs
+		n.NameToken = token
+		return n
+	}
 	if tokenizer.Prefetch(1).Test(TokenLparen) {
 		// append関数の呼び出し
 		if tokenizer.Fetch().str == "append" {
 			tokenizer.Expect(TokenIdentifier)
-			tokenizer.Expect(TokenLparen)
-			var arg1 = expr()
-			tokenizer.Expect(TokenComma)
-			var arg2 = expr()
-			tokenizer.Expect(TokenRparen)
-			return NewAppendCallNode(arg1, arg2)
+			arguments, hasEllipsis := argumentList()
+			return NewAppendCallNode(arguments, hasEllipsis)
 		}
 		// string関数の呼び出し
 		if tokenizer.Fetch().str == "string" {
@@ -835,13 +1647,13 @@ func named() *Node {
 			tokenizer.Expect(TokenRparen)
 			return NewStringCallNode(arg)
 		}
-		// rune関数の呼び出し
-		if tokenizer.Fetch().str == "rune" {
+		// new関数の呼び出し
+		if tokenizer.Fetch().str == "new" {
 			tokenizer.Expect(TokenIdentifier)
 			tokenizer.Expect(TokenLparen)
-			var arg = expr()
+			var ty = type_()
 			tokenizer.Expect(TokenRparen)
-			return NewRuneCallNode(arg)
+			return NewNewCallNode(ty)
 		}
 		// len関数の呼び出し
 		if tokenizer.Fetch().str == "len" {
@@ -853,28 +1665,77 @@ func named() *Node {
 		}
 
 		// 関数呼び出し
+		var token = tokenizer.Fetch()
 		var functionName = identifier()
-		tokenizer.Expect(TokenLparen)
-		var arguments = []*Node{}
-		for !tokenizer.Consume(TokenRparen) {
-			if len(arguments) > 0 {
-				tokenizer.Expect(TokenComma)
-			}
-			arguments = append(arguments, expr())
-		}
-		return NewFunctionCallNode(functionName, arguments)
+		arguments, hasEllipsis := argumentList()
+		n := NewFunctionCallNode(functionName, arguments)
+		n.HasEllipsis = hasEllipsis
+		n.NameToken = token
+		return n
 	}
 	return variableRef()
 }
 
+// パッケージpで名前nameの型パラメータを持つ関数が宣言されているかどうか
+// pが読んでいるパッケージの場合は、ドットインポートしたパッケージの関数も探す
+func declaresGenericFunction(p *Program, name string) bool {
+	if p.FindGenericFunction(name) != nil {
+		return true
+	}
+	var src = Env.Source()
+	if p != Env.program || src == nil || !util.IsExported(name) {
+		return false
+	}
+	for _, imp := range src.DotImports() {
+		if q := findProgram(imp.Path); q != nil && q.FindGenericFunction(name) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// "(" (expr ("," expr)* "..."?)? ")" を読み、引数のリストと
+// 最後の引数が展開されているかどうかを返す
+func argumentList() ([]*Node, bool) {
+	tokenizer.Expect(TokenLparen)
+	var arguments = []*Node{}
+	var hasEllipsis = false
+	for !tokenizer.Consume(TokenRparen) {
+		if len(arguments) > 0 {
+			tokenizer.Expect(TokenComma)
+		}
+		if hasEllipsis {
+			BadToken(tokenizer.Fetch(), "...で展開できるのは最後の引数だけです")
+		}
+		arguments = append(arguments, expr())
+		if tokenizer.Consume(TokenEllipsis) {
+			hasEllipsis = true
+		}
+	}
+	return arguments, hasEllipsis
+}
+
+// ローカル変数の名前かどうか。ローカル変数はインポートしたパッケージの名前を隠す
+func isLocalVar(name string) bool {
+	var v = Env.FindVar(name)
+	return v != nil && v.Kind == lang.VariableLocal
+}
+
 func variableRef() *Node {
 	ident := identifier()
+	if ident == "_" {
+		// 代入の左辺にだけ書ける
+		return NewLeafNode(NodeBlank)
+	}
 	var v = Env.FindVar(ident)
 	if v != nil && v.Kind == lang.VariableLocal {
 		var node = NewLeafNode(NodeLocalVariable)
 		node.Variable = v
 		return node
 	}
+	if v == nil && ident == "nil" {
+		return NewLeafNode(NodeNil)
+	}
 	var node = NewLeafNode(NodeTopLevelVariable)
 	node.Label = ident
 	return node
@@ -882,19 +1743,35 @@ func variableRef() *Node {
 
 func localVariableDeclaration() *Node {
 	var token = tokenizer.Fetch()
-	var ident = identifier()
+	identifier()
+	return declareLocalVariable(token)
+}
+
+// tokenが表す名前のローカル変数を現在のスコープに宣言する
+// _の場合は名前で参照できない変数を宣言する
+func declareLocalVariable(token Token) *Node {
 	var node = NewLeafNode(NodeLocalVariable)
-	lvar := Env.FindLocalVar(ident)
+	if token.str == "_" {
+		node.Variable = Env.AddBlankLocalVar(lang.NewUndefinedType())
+		return node
+	}
+	lvar := Env.FindLocalVar(token.str)
 	if lvar != nil {
 		BadToken(token, "すでに定義済みの変数です")
 	}
-	node.Variable = Env.AddLocalVar(lang.NewUndefinedType(), ident)
+	node.Variable = Env.AddLocalVar(lang.NewUndefinedType(), token.str)
 	return node
 }
 
 func topLevelVariableDeclaration() *Node {
 	var token = tokenizer.Fetch()
 	var ident = identifier()
+	if ident == "_" {
+		// 初期化式は評価されるが、値はどこにも保存されない
+		var node = NewLeafNode(NodeBlank)
+		node.ExprType = lang.NewUndefinedType()
+		return node
+	}
 	var node = NewLeafNode(NodeTopLevelVariable)
 	tvar := Env.program.FindTopLevelVariable(ident)
 	if tvar != nil {
@@ -2,16 +2,28 @@ package parse
 
 import (
 	"strconv"
+	"strings"
 
 	"github.com/myuu222/myuugo/compiler/lang"
+	"github.com/myuu222/myuugo/compiler/util"
 )
 
 type Program struct {
-	Name              string // パッケージ名
+	Name              string   // パッケージ名。mainパッケージ以外はインポートパス
+	PackageName       string   // package文に書かれたパッケージ名
+	IsLibrary         bool     // 標準ライブラリのパッケージかどうか
+	IgnoredFiles      []string // ビルドの条件に合わないので読まなかったファイル
 	TopLevelVariables []*lang.Variable
 	Functions         []*lang.Function
 	Sources           []*Source
-	Traversed         bool
+	InitFunctions     []string // init関数のラベル。宣言された順に並ぶ
+	InitOrder         []*Node  // 初期化式を持つトップレベルのvar文。初期化する順に並ぶ
+	GenericFunctions  []*Generic
+	GenericTypes      []*Generic
+	Constraints       map[string]*lang.Constraint // interfaceで宣言された型パラメータの制約
+	aliases           []*typeAlias
+	localTypes        map[string]int // 関数ごとの、その中で宣言された型の数
+	env               *Environment   // パッケージのスコープ
 
 	// そのうち削除するかも
 	StringLiterals   []*lang.StringLiteral
@@ -20,11 +32,12 @@ type Program struct {
 
 func NewProgram() *Program {
 	return &Program{
-		Traversed:         false,
 		TopLevelVariables: []*lang.Variable{},
 		Functions:         []*lang.Function{},
 		StringLiterals:    []*lang.StringLiteral{},
 		Sources:           []*Source{},
+		Constraints:       map[string]*lang.Constraint{},
+		localTypes:        map[string]int{},
 	}
 }
 
@@ -62,20 +75,61 @@ func (p *Program) FindFunction(name string) *lang.Function {
 	return nil
 }
 
-func (p *Program) RegisterType(udt lang.Type) {
-	_, ok := p.FindType(udt.DefinedName)
-	if ok {
-		panic("型" + udt.DefinedName + "は既に定義されています")
+// 型の名前だけを登録しておく
+// 元になる型はtype文を読んだときに埋めるので、宣言の順番によらず型を参照できる
+func (p *Program) DeclareType(name string) lang.Type {
+	udt := lang.NewUserDefinedType(name, lang.NewUndefinedType())
+	udt.Package = p.Name
+	p.UserDefinedTypes = append(p.UserDefinedTypes, udt)
+	return udt
+}
+
+// パッケージ内のすべての型の定義が揃ったあとに呼び出し、構造体のメンバーの配置を決める
+func (p *Program) LayoutTypes() {
+	for _, udt := range p.UserDefinedTypes {
+		checkTypeCycle(udt, []*lang.Type{})
+	}
+	for _, udt := range p.UserDefinedTypes {
+		layoutType(udt.PtrTo)
+	}
+	for _, a := range p.aliases {
+		layoutType(&a.ty)
 	}
+}
 
-	// 元が構造体だった場合はオフセットを修正
-	if udt.PtrTo.Kind == lang.TypeStruct {
-		entityType := udt.PtrTo
-		for i := 1; i < len(entityType.MemberNames); i++ {
-			entityType.MemberOffsets[i] = entityType.MemberOffsets[i-1] + lang.Sizeof(entityType.MemberTypes[i-1])
+// 型の定義がポインタやスライスを経由せずに自分自身を含んでいる場合はエラーにする
+func checkTypeCycle(ty lang.Type, path []*lang.Type) {
+	switch ty.Kind {
+	case lang.TypeUserDefined:
+		for _, t := range path {
+			if t == ty.PtrTo {
+				util.Alarm("型%sの定義が循環しています", ty.DefinedName)
+			}
+		}
+		checkTypeCycle(*ty.PtrTo, append(path, ty.PtrTo))
+	case lang.TypeArray:
+		checkTypeCycle(*ty.PtrTo, path)
+	case lang.TypeStruct:
+		for _, memberType := range ty.MemberTypes {
+			checkTypeCycle(memberType, path)
+		}
+	}
+}
+
+// 構造体のメンバーのオフセットを計算する
+// 名前のない構造体の中に含まれている構造体も同様に計算する
+func layoutType(ty *lang.Type) {
+	switch ty.Kind {
+	case lang.TypePtr, lang.TypeArray, lang.TypeSlice:
+		layoutType(ty.PtrTo)
+	case lang.TypeStruct:
+		for i := 1; i < len(ty.MemberNames); i++ {
+			ty.MemberOffsets[i] = ty.MemberOffsets[i-1] + lang.Sizeof(ty.MemberTypes[i-1])
+		}
+		for i := range ty.MemberTypes {
+			layoutType(&ty.MemberTypes[i])
 		}
 	}
-	p.UserDefinedTypes = append(p.UserDefinedTypes, udt)
 }
 
 func (p *Program) FindType(name string) (lang.Type, bool) {
@@ -84,12 +138,195 @@ func (p *Program) FindType(name string) (lang.Type, bool) {
 			return t, true
 		}
 	}
+	for _, a := range p.aliases {
+		if a.name == name {
+			return a.resolve(), true
+		}
+	}
 	return lang.Type{}, false
 }
 
+// パッケージで宣言された型、型エイリアス、ジェネリック型の名前を返す
+func (p *Program) TypeNames() []string {
+	var names = []string{}
+	for _, t := range p.UserDefinedTypes {
+		names = append(names, t.DefinedName)
+	}
+	for _, a := range p.aliases {
+		names = append(names, a.name)
+	}
+	for _, g := range p.GenericTypes {
+		names = append(names, g.Name)
+	}
+	return names
+}
+
+// 型や制約の名前としてすでに宣言されているかどうか
+func (p *Program) isTypeNameDeclared(name string) bool {
+	for _, t := range p.UserDefinedTypes {
+		if t.DefinedName == name {
+			return true
+		}
+	}
+	for _, a := range p.aliases {
+		if a.name == name {
+			return true
+		}
+	}
+	_, isConstraint := p.Constraints[name]
+	return isConstraint || p.FindGenericType(name) != nil
+}
+
+// type A = B で宣言された、型Bの別名
+// 別名の先の型は宣言の順番によらず参照できるように、最初に必要になったときに読む
+type typeAlias struct {
+	name      string
+	ty        lang.Type
+	resolved  bool
+	resolving bool
+
+	tokenizer *Tokenizer
+	pos       int // 名前のトークンの位置
+	env       *Environment
+}
+
+func (a *typeAlias) resolve() lang.Type {
+	if a.resolved {
+		return a.ty
+	}
+	if a.resolving {
+		BadToken(a.tokenizer.tokens[a.pos], "型エイリアス"+a.name+"の定義が循環しています")
+	}
+	a.resolving = true
+	reparseAt(a.tokenizer, a.pos, a.env, nil, func() {
+		identifier()
+		tokenizer.Expect(TokenEqual)
+		a.ty = type_()
+	})
+	a.resolved = true
+	return a.ty
+}
+
+// すべての型エイリアスの別名の先の型を決める
+func (p *Program) resolveAliases() {
+	for _, a := range p.aliases {
+		a.resolve()
+	}
+}
+
+func (p *Program) FindGenericFunction(name string) *Generic {
+	for _, g := range p.GenericFunctions {
+		if g.Name == name {
+			return g
+		}
+	}
+	return nil
+}
+
+func (p *Program) FindGenericType(name string) *Generic {
+	for _, g := range p.GenericTypes {
+		if g.Name == name {
+			return g
+		}
+	}
+	return nil
+}
+
+// 文字列リテラルのラベルの通し番号。複数のパッケージを1つのアセンブリに出力するので、パッケージをまたいで数える
+var stringLiteralCount = 0
+
 func (p *Program) AddStringLiteral(value string) *lang.StringLiteral {
-	var label = ".LStr" + strconv.Itoa(len(p.StringLiterals))
+	var label = ".LStr" + strconv.Itoa(stringLiteralCount)
+	stringLiteralCount++
 	var str = lang.NewStringLiteral(label, value)
 	p.StringLiterals = append(p.StringLiterals, str)
 	return str
 }
+
+// パッケージの中で名前nameの関数、変数、型のいずれかが宣言されているかどうか
+func (p *Program) Declares(name string) bool {
+	return p.FindFunction(name) != nil || p.FindGenericFunction(name) != nil || p.FindTopLevelVariable(name) != nil || p.isTypeNameDeclared(name)
+}
+
+// インポートしたパッケージの名前が、パッケージで宣言された名前と衝突していないか調べる
+func (p *Program) checkImportNames() {
+	for _, s := range p.Sources {
+		for _, imp := range s.Imports {
+			if imp.Name == "." || imp.Name == "_" {
+				continue
+			}
+			if p.Declares(imp.Name) {
+				BadToken(imp.Token, imp.Name+"はパッケージの中で宣言されている名前と衝突しています")
+			}
+		}
+	}
+}
+
+// パッケージのインポート。fromのtokenの位置でtoをインポートしている
+type importEdge struct {
+	from  *Program
+	to    *Program
+	token Token
+}
+
+// パッケージがインポートしているパッケージを、インポートが書かれた順に返す
+func importsOf(p *Program, programs []*Program) []importEdge {
+	var edges = []importEdge{}
+	for _, s := range p.Sources {
+		for _, pkg := range s.Packages {
+			for _, dep := range programs {
+				if dep.Name == pkg {
+					edges = append(edges, importEdge{from: p, to: dep, token: s.ImportToken(pkg)})
+				}
+			}
+		}
+	}
+	return edges
+}
+
+// 依存しているパッケージが先に来るようにプログラムを並べる
+// インポートが循環していればエラーにする
+func DependencyOrder(programs []*Program) []*Program {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	var order = []*Program{}
+	var state = map[*Program]int{}
+	var path = []importEdge{} // 探索中のパッケージまでたどってきたインポート
+	var visit func(p *Program)
+	visit = func(p *Program) {
+		state[p] = visiting
+		for _, edge := range importsOf(p, programs) {
+			switch state[edge.to] {
+			case unvisited:
+				path = append(path, edge)
+				visit(edge.to)
+				path = path[:len(path)-1]
+			case visiting:
+				reportImportCycle(append(path, edge), edge.to)
+			}
+		}
+		state[p] = visited
+		order = append(order, p)
+	}
+	for _, p := range programs {
+		if state[p] == unvisited {
+			visit(p)
+		}
+	}
+	return order
+}
+
+// たどってきたインポートのうち、パッケージstartから始まる循環を表示する
+func reportImportCycle(path []importEdge, start *Program) {
+	for len(path) > 0 && path[0].from != start {
+		path = path[1:]
+	}
+	var lines = []string{"import cycle not allowed", "package " + start.Name}
+	for _, edge := range path {
+		lines = append(lines, "\t"+edge.token.Position()+": imports "+edge.to.Name)
+	}
+	util.Alarm("%s", strings.Join(lines, "\n"))
+}
@@ -1,34 +1,86 @@
 package parse
 
-import (
-	"strings"
-)
-
 type Source struct {
 	FileName string
-	Packages []string
+	Packages []string // インポートしたパッケージのパス
+	Imports  []*Import
 	Code     []*Node
+
+	tokenizer *Tokenizer
+	env       *Environment // ファイルのスコープ
+}
+
+// import文で読み込んだパッケージ
+type Import struct {
+	Path  string // インポートパス
+	Name  string // ソースの中でパッケージを参照する名前。ドットインポートなら"."、ブランクインポートなら"_"
+	Alias bool   // 名前を明示してインポートしたかどうか
+	Token Token  // インポートパスが書かれたトークン
+	Used  bool   // ソースの中で参照されたかどうか
 }
 
 func NewSource(fileName string) *Source {
-	return &Source{FileName: fileName, Packages: []string{}, Code: []*Node{}}
+	return &Source{FileName: fileName, Packages: []string{}, Imports: []*Import{}, Code: []*Node{}}
+}
+
+// パッケージをインポートする
+// nameが空の場合、インポートしたパッケージのpackage文を読んでからnameImportsで名前を決める
+func (s *Source) AddImport(path string, name string, token Token) {
+	s.Imports = append(s.Imports, &Import{Path: path, Name: name, Alias: name != "", Token: token})
+	if !includes(s.Packages, path) {
+		s.Packages = append(s.Packages, path)
+	}
+}
+
+// 名前を明示せずにインポートしたパッケージを、そのパッケージのpackage文に書かれた名前で参照できるようにする
+// 同じ名前で複数のパッケージをインポートしていればエラーにする
+func (s *Source) nameImports(programs []*Program) {
+	var names = []string{}
+	for _, imp := range s.Imports {
+		if !imp.Alias {
+			for _, p := range programs {
+				if p.Name == imp.Path {
+					imp.Name = p.PackageName
+				}
+			}
+		}
+		if imp.Name == "." || imp.Name == "_" {
+			continue
+		}
+		if includes(names, imp.Name) {
+			BadToken(imp.Token, imp.Name+"は既にインポートされています")
+		}
+		names = append(names, imp.Name)
+	}
 }
 
-func (s *Source) AddPackage(name string) string {
-	pkg, ok := s.FindPackage(name)
-	if ok {
-		return pkg
+// ソースの中で名前nameで参照できるパッケージを探す
+func (s *Source) FindPackage(name string) (*Import, bool) {
+	for _, imp := range s.Imports {
+		if imp.Name == name {
+			return imp, true
+		}
+	}
+	return nil, false
+}
+
+// ドットインポートしたパッケージを返す
+func (s *Source) DotImports() []*Import {
+	var imports = []*Import{}
+	for _, imp := range s.Imports {
+		if imp.Name == "." {
+			imports = append(imports, imp)
+		}
 	}
-	s.Packages = append(s.Packages, name)
-	return name
+	return imports
 }
 
-func (s *Source) FindPackage(name string) (string, bool) {
-	for _, pkg := range s.Packages {
-		sections := strings.Split(pkg, "/")
-		if sections[len(sections)-1] == name {
-			return pkg, true
+// パッケージpathをインポートしている位置を返す
+func (s *Source) ImportToken(path string) Token {
+	for _, imp := range s.Imports {
+		if imp.Path == path {
+			return imp.Token
 		}
 	}
-	return "", false
+	return Token{}
 }
@@ -36,6 +36,9 @@ const (
 	TokenPackage            TokenKind = "package"
 	TokenType               TokenKind = "type"
 	TokenImport             TokenKind = "import"
+	TokenSwitch             TokenKind = "switch"
+	TokenCase               TokenKind = "case"
+	TokenDefault            TokenKind = "default"
 	TokenEqual              TokenKind = "="
 	TokenDoubleEqual        TokenKind = "=="
 	TokenNotEqual           TokenKind = "!="
@@ -65,6 +68,9 @@ const (
 	TokenDot                TokenKind = "."
 	TokenColon              TokenKind = ":"
 	TokenPercent            TokenKind = "%"
+	TokenEllipsis           TokenKind = "..."
+	TokenVerticalLine       TokenKind = "|"
+	TokenTilde              TokenKind = "~"
 )
 
 type Token struct {
@@ -83,6 +89,11 @@ func NewToken(kind TokenKind, str string, rest string) Token {
 	return Token{kind: kind, str: str, rest: rest, path: filename}
 }
 
+// トークンの位置を「ファイル名:行番号」の形で返す
+func (t Token) Position() string {
+	return util.Position(t.path, t.rest)
+}
+
 func BadToken(token Token, message string) {
 	util.ErrorAt(token.path, token.rest, message)
 }
@@ -104,15 +115,16 @@ func (t *Tokenizer) Tokenize(path string) {
 	var input = userInput
 
 	var symbols = []TokenKind{
-		TokenDoubleEqual, TokenNotEqual, TokenGreaterEqual, TokenLessEqual, TokenColonEqual, TokenDoubleAmpersand, TokenDoubleVerticalLine,
+		TokenEllipsis, TokenDoubleEqual, TokenNotEqual, TokenGreaterEqual, TokenLessEqual, TokenColonEqual, TokenDoubleAmpersand, TokenDoubleVerticalLine,
 		TokenPlus, TokenMinus, TokenStar, TokenSlash, TokenLparen, TokenRparen, TokenLess, TokenGreater, TokenSemicolon, TokenNewLine, TokenEqual, TokenLbrace, TokenRbrace, TokenComma, TokenAmpersand, TokenLSBrace, TokenRSBrace, TokenBang, TokenDot, TokenColon, TokenPercent,
+		TokenVerticalLine, TokenTilde,
 	}
 	var keywords = []TokenKind{
 		TokenPackage,
 		TokenReturn, TokenImport,
 		TokenFunc, TokenElse, TokenType,
 		TokenFor, TokenVar,
-		TokenIf,
+		TokenIf, TokenSwitch, TokenCase, TokenDefault,
 	}
 
 	for input != "" {
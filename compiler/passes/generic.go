@@ -0,0 +1,155 @@
+package passes
+
+import (
+	"github.com/myuu222/myuugo/compiler/lang"
+	"github.com/myuu222/myuugo/compiler/parse"
+)
+
+// パッケージpのジェネリック関数の呼び出しの型引数を決め、インスタンス化した関数を呼び出すように書き換える
+// 明示されていない型引数は、実引数の型から推論する。呼び出す関数を返す
+func instantiateCall(node *parse.Node, p *parse.Program, g *parse.Generic) *lang.Function {
+	var fn = g.Signature
+	if len(node.TypeArguments) > len(g.TypeParams) {
+		parse.BadToken(node.NameToken, "関数"+g.Name+"の型引数が多すぎます")
+	}
+	var typeArgs = make([]lang.Type, len(g.TypeParams))
+	for i := range typeArgs {
+		typeArgs[i] = lang.NewUndefinedType()
+	}
+	copy(typeArgs, node.TypeArguments)
+
+	// 実引数と、それを受け取る引数の型の組
+	var paramTypes, argTypes = []lang.Type{}, []lang.Type{}
+	for i, argument := range node.Arguments {
+		var paramType lang.Type
+		if fn.IsVariadic && i >= len(fn.ParameterTypes)-1 {
+			paramType = fn.ParameterTypes[len(fn.ParameterTypes)-1]
+			if !node.HasEllipsis {
+				paramType = *paramType.PtrTo
+			}
+		} else if i < len(fn.ParameterTypes) {
+			paramType = fn.ParameterTypes[i]
+		} else {
+			break
+		}
+		paramTypes = append(paramTypes, paramType)
+		argTypes = append(argTypes, traverse(argument))
+	}
+
+	// 型の決まっている実引数から推論し、それでも決まらなければ型のない定数の既定の型を使う
+	for i := range paramTypes {
+		if !argTypes[i].Untyped {
+			unify(g, typeArgs, paramTypes[i], argTypes[i])
+		}
+	}
+	for i := range paramTypes {
+		if argTypes[i].Untyped && argTypes[i].Kind != lang.TypeNil {
+			unify(g, typeArgs, paramTypes[i], lang.DefaultType(argTypes[i]))
+		}
+	}
+
+	for i, ty := range typeArgs {
+		if ty.Kind == lang.TypeUndefined {
+			parse.BadToken(node.NameToken, "関数"+g.Name+"の型パラメータ"+g.TypeParams[i]+"の型を推論できません")
+		}
+		if !g.Constraints[i].SatisfiedBy(ty) {
+			parse.BadToken(node.NameToken, "型"+ty.String()+"は関数"+g.Name+"の型パラメータ"+g.TypeParams[i]+"の制約"+g.Constraints[i].String()+"を満たしていません")
+		}
+	}
+
+	for _, ty := range typeArgs {
+		if parse.ContainsTypeParameter(ty) {
+			// 型パラメータを持つ関数の本体を検査している。インスタンス化せずに、型パラメータを型引数に置き換えた型で検査する
+			return substituteSignature(g, typeArgs)
+		}
+	}
+
+	inst, created := parse.InstantiateFunction(g, typeArgs)
+	if created {
+		// インスタンスは関数を宣言したパッケージの関数として調べる
+		var caller = program
+		program = p
+		traverse(inst.Node)
+		program = caller
+	}
+	node.Label = inst.Label
+	return p.FindFunction(inst.Label)
+}
+
+// 型パラメータを持つ関数の引数と返り値の型の、型パラメータを型引数typeArgsに置き換えた関数を返す
+func substituteSignature(g *parse.Generic, typeArgs []lang.Type) *lang.Function {
+	var parameterTypes = []lang.Type{}
+	for _, ty := range g.Signature.ParameterTypes {
+		parameterTypes = append(parameterTypes, substitute(g, typeArgs, ty))
+	}
+	var fn = lang.NewFunction(g.Name, parameterTypes, substitute(g, typeArgs, g.Signature.ReturnValueType))
+	fn.IsVariadic = g.Signature.IsVariadic
+	return fn
+}
+
+// 型tyに含まれる、関数gの型パラメータを型引数typeArgsに置き換える
+func substitute(g *parse.Generic, typeArgs []lang.Type, ty lang.Type) lang.Type {
+	switch ty.Kind {
+	case lang.TypeParameter:
+		for i, name := range g.TypeParams {
+			if name == ty.DefinedName {
+				return typeArgs[i]
+			}
+		}
+	case lang.TypePtr, lang.TypeSlice, lang.TypeArray:
+		var elemType = substitute(g, typeArgs, *ty.PtrTo)
+		ty.PtrTo = &elemType
+	case lang.TypeMultiple:
+		var components = []lang.Type{}
+		for _, c := range ty.Components {
+			components = append(components, substitute(g, typeArgs, c))
+		}
+		ty.Components = components
+	case lang.TypeStruct:
+		var memberTypes = []lang.Type{}
+		for _, memberType := range ty.MemberTypes {
+			memberTypes = append(memberTypes, substitute(g, typeArgs, memberType))
+		}
+		ty.MemberTypes = memberTypes
+	case lang.TypeUserDefined:
+		if ty.Origin != "" && parse.ContainsTypeParameter(ty) {
+			var args = []lang.Type{}
+			for _, arg := range ty.TypeArguments {
+				args = append(args, substitute(g, typeArgs, arg))
+			}
+			return parse.ReinstantiateType(ty, args)
+		}
+	}
+	return ty
+}
+
+// 型パラメータを含む引数の型paramTypeと実引数の型argTypeを照らし合わせて、型パラメータに対応する型を見つける
+// 決まった型が合わない場合は、インスタンス化したあとの引数の型の検査でエラーになる
+func unify(g *parse.Generic, typeArgs []lang.Type, paramType lang.Type, argType lang.Type) {
+	switch paramType.Kind {
+	case lang.TypeParameter:
+		for i, name := range g.TypeParams {
+			if name == paramType.DefinedName && typeArgs[i].Kind == lang.TypeUndefined {
+				typeArgs[i] = argType
+			}
+		}
+	case lang.TypePtr, lang.TypeSlice, lang.TypeArray:
+		var u = lang.Underlying(argType)
+		if u.Kind == paramType.Kind {
+			unify(g, typeArgs, *paramType.PtrTo, *u.PtrTo)
+		}
+	case lang.TypeUserDefined:
+		if paramType.Origin != "" && paramType.Origin == argType.Origin {
+			for i := range paramType.TypeArguments {
+				unify(g, typeArgs, paramType.TypeArguments[i], argType.TypeArguments[i])
+			}
+		}
+	case lang.TypeStruct:
+		var u = lang.Underlying(argType)
+		if u.Kind == lang.TypeStruct && len(u.MemberTypes) == len(paramType.MemberTypes) {
+			for i := range paramType.MemberTypes {
+				unify(g, typeArgs, paramType.MemberTypes[i], u.MemberTypes[i])
+			}
+		}
+	}
+}
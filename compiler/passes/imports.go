@@ -0,0 +1,66 @@
+package passes
+
+import (
+	"path"
+
+	"github.com/myuu222/myuugo/compiler/parse"
+	"github.com/myuu222/myuugo/compiler/util"
+)
+
+// nodeを含むファイルにドットインポートしたパッケージのうち、名前nameを公開しているものを探す
+// declaresはパッケージがnameを宣言しているかどうかを返す
+func findDotImported(node *parse.Node, name string, declares func(p *parse.Program) bool) *parse.Program {
+	var source = node.Env.Source()
+	if source == nil || !util.IsExported(name) {
+		return nil
+	}
+	for _, imp := range source.DotImports() {
+		if p := packageToProgram(imp.Path); p != nil && declares(p) {
+			imp.Used = true
+			return p
+		}
+	}
+	return nil
+}
+
+// ドットインポートしたパッケージが公開している名前が、パッケージで宣言された名前と衝突していないか調べる
+func checkDotImportConflicts(p *parse.Program) {
+	for _, source := range p.Sources {
+		for _, imp := range source.DotImports() {
+			var imported = packageToProgram(imp.Path)
+			var names = []string{}
+			for _, fn := range imported.Functions {
+				if fn.IsDefined {
+					names = append(names, fn.Label)
+				}
+			}
+			for _, g := range imported.GenericFunctions {
+				names = append(names, g.Name)
+			}
+			for _, v := range imported.TopLevelVariables {
+				names = append(names, v.Name)
+			}
+			names = append(names, imported.TypeNames()...)
+			for _, name := range names {
+				if util.IsExported(name) && p.Declares(name) {
+					parse.BadToken(imp.Token, name+"はドットインポートしたパッケージ"+imp.Path+"の名前と衝突しています")
+				}
+			}
+		}
+	}
+}
+
+// 参照されなかったインポートをエラーにする
+func checkUnusedImports(p *parse.Program) {
+	for _, source := range p.Sources {
+		for _, imp := range source.Imports {
+			if imp.Used || imp.Name == "_" {
+				continue
+			}
+			if imp.Name != "." && imp.Name != path.Base(imp.Path) {
+				parse.BadToken(imp.Token, "\""+imp.Path+"\" imported as "+imp.Name+" and not used")
+			}
+			parse.BadToken(imp.Token, "\""+imp.Path+"\" imported and not used")
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package passes
+
+import (
+	"strings"
+
+	"github.com/myuu222/myuugo/compiler/parse"
+	"github.com/myuu222/myuugo/compiler/util"
+)
+
+// ノードの直下にある子ノードをすべて返す
+func childNodes(node *parse.Node) []*parse.Node {
+	var nodes = []*parse.Node{}
+	nodes = append(nodes, node.Children...)
+	for _, n := range []*parse.Node{
+		node.Lhs, node.Rhs, node.Seq, node.Index, node.If, node.Else, node.Body,
+		node.Condition, node.Init, node.Update, node.Target, node.Owner,
+	} {
+		if n != nil {
+			nodes = append(nodes, n)
+		}
+	}
+	nodes = append(nodes, node.Parameters...)
+	nodes = append(nodes, node.Results...)
+	nodes = append(nodes, node.Arguments...)
+	nodes = append(nodes, node.MemberValues...)
+	return nodes
+}
+
+// nodeの中で参照されているパッケージ変数の名前をreferencesに集める
+// 同じパッケージの関数を呼び出している場合は、その関数の本体で参照されているものも含める
+func collectReferences(node *parse.Node, functions map[string]*parse.Node, visited map[string]bool, references map[string]bool) {
+	if node.Kind == parse.NodePackageDot {
+		// 他のパッケージの変数は初期化の順序に関係しない
+		return
+	}
+	if node.Kind == parse.NodeTopLevelVariable {
+		references[node.Label] = true
+	}
+	if node.Kind == parse.NodeFunctionCall && !visited[node.Label] {
+		visited[node.Label] = true
+		if fn, ok := functions[node.Label]; ok {
+			collectReferences(fn, functions, visited, references)
+		}
+	}
+	for _, c := range childNodes(node) {
+		collectReferences(c, functions, visited, references)
+	}
+}
+
+// パッケージ変数を初期化する順序を決める
+// まだ初期化されていない変数に依存していないもののうち、宣言された順で最も早いものから順に初期化する
+func initializationOrder(p *parse.Program) []*parse.Node {
+	var decls = []*parse.Node{}
+	var functions = map[string]*parse.Node{}
+	for _, source := range p.Sources {
+		for _, node := range source.Code {
+			if node.Kind == parse.NodeTopLevelVarStmt && len(node.Children) == 2 {
+				decls = append(decls, node)
+			}
+			if node.Kind == parse.NodeFunctionDef {
+				functions[node.Label] = node
+			}
+		}
+	}
+
+	var dependencies = map[*parse.Node]map[string]bool{}
+	var uninitialized = map[string]bool{}
+	for _, decl := range decls {
+		var references = map[string]bool{}
+		collectReferences(decl.Children[1], functions, map[string]bool{}, references)
+		dependencies[decl] = references
+		for _, name := range declaredNames(decl) {
+			uninitialized[name] = true
+		}
+	}
+
+	var order = []*parse.Node{}
+	var done = map[*parse.Node]bool{}
+	for len(order) < len(decls) {
+		var next *parse.Node
+		for _, decl := range decls {
+			if done[decl] {
+				continue
+			}
+			var ready = true
+			for ref := range dependencies[decl] {
+				if uninitialized[ref] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				next = decl
+				break
+			}
+		}
+		if next == nil {
+			var names = []string{}
+			for _, decl := range decls {
+				if !done[decl] {
+					names = append(names, declaredNames(decl)...)
+				}
+			}
+			util.Alarm("パッケージ%sの変数の初期化が循環しています: %s", p.Name, strings.Join(names, ", "))
+		}
+		for _, name := range declaredNames(next) {
+			uninitialized[name] = false
+		}
+		done[next] = true
+		order = append(order, next)
+	}
+	return order
+}
+
+// var文で宣言されている変数の名前を返す
+func declaredNames(decl *parse.Node) []string {
+	var vars = []*parse.Node{decl.Children[0]}
+	if decl.Children[0].Kind == parse.NodeExprList {
+		vars = decl.Children[0].Children
+	}
+	var names = []string{}
+	for _, v := range vars {
+		if v.Kind != parse.NodeBlank {
+			names = append(names, v.Label)
+		}
+	}
+	return names
+}
@@ -30,46 +30,41 @@ func alignLocalVars(functionName string) {
 	}
 }
 
-func ready(p *parse.Program) bool {
-	var imported = []string{}
-	for _, s := range p.Sources {
-		imported = append(imported, s.Packages...)
-	}
-	for _, i := range imported {
-		for _, prog := range programs {
-			if prog.Name == i && !prog.Traversed {
-				return false
-			}
-		}
-	}
-	return true
-}
-
+// インポートされているパッケージから順に型を決めていく
 func Semantic(ps []*parse.Program) {
 	programs = ps
-	for {
-		var ok = true
-
-		for _, p := range programs {
-			if p.Traversed {
-				continue
-			}
+	for _, p := range parse.DependencyOrder(programs) {
+		program = p
+		traverseProgram(p)
+	}
+}
 
-			ok = false
-			if ready(p) {
-				program = p
-				for _, source := range p.Sources {
-					for _, node := range source.Code {
-						traverse(node)
-					}
-				}
-				p.Traversed = true
+func traverseProgram(p *parse.Program) {
+	checkDotImportConflicts(p)
+	// 初期化式を持つパッケージ変数の型は、初期化される順に決めていく
+	p.InitOrder = initializationOrder(p)
+	for _, source := range p.Sources {
+		for _, node := range source.Code {
+			if node.Kind == parse.NodeTopLevelVarStmt && len(node.Children) == 1 {
+				traverse(node)
 			}
 		}
-		if ok {
-			break
+	}
+	for _, node := range p.InitOrder {
+		traverse(node)
+	}
+	// 型パラメータを持つ関数の本体は、インスタンス化する前に一度だけ、型パラメータのまま検査する
+	for _, g := range p.GenericFunctions {
+		traverse(g.Definition)
+	}
+	for _, source := range p.Sources {
+		for _, node := range source.Code {
+			if node.Kind != parse.NodeTopLevelVarStmt {
+				traverse(node)
+			}
 		}
 	}
+	checkUnusedImports(p)
 }
 
 // 式の型を決定するのに使う
@@ -101,7 +96,6 @@ func traverse(node *parse.Node) lang.Type {
 	}
 	if node.Kind == parse.NodePackageDot {
 		node.ExprType = traverse(node.Children[0])
-		node.Children[0].In = node.Label
 		return node.ExprType
 	}
 	if node.Kind == parse.NodeLocalVarList {
@@ -137,10 +131,15 @@ func traverse(node *parse.Node) lang.Type {
 			if node.Target != nil {
 				util.Alarm("返り値の型がvoid型の関数内でreturnに引数を渡すことはできません")
 			}
+		} else if node.Target == nil {
+			// 名前付きの返り値を持つ関数では空のreturn文が許される
+			if len(fn.ResultVariables) == 0 {
+				util.Alarm("関数%sのreturn文には返り値が必要です", fn.Label)
+			}
 		} else {
 			var ty = traverse(node.Target)
-			if !lang.TypeCompatable(fn.ReturnValueType, ty) {
-				util.Alarm("返り値の型とreturnの引数の型が一致しません")
+			if !lang.AssignableTo(ty, fn.ReturnValueType) {
+				util.Alarm("関数%sの返り値の型は%sですが、returnに型%sの値が渡されています", fn.Label, fn.ReturnValueType, ty)
 			}
 		}
 		node.ExprType = stmtType
@@ -149,12 +148,26 @@ func traverse(node *parse.Node) lang.Type {
 	if node.Kind == parse.NodeAssign {
 		var lhs = node.Children[0]
 		var rhs = node.Children[1]
-		var ltype = traverse(lhs)
 		var rtype = traverse(rhs)
+		var rtypes = []lang.Type{rtype}
+		if rtype.Kind == lang.TypeMultiple {
+			rtypes = rtype.Components
+		}
+		if len(lhs.Children) != len(rtypes) {
+			util.Alarm("代入式の左辺と右辺の値の数が異なります")
+		}
 
-		if !lang.TypeCompatable(ltype, rtype) {
-			util.Alarm("代入式の左辺と右辺の型が違います ")
+		for i, l := range lhs.Children {
+			if l.Kind == parse.NodeBlank {
+				// _にはどんな値でも代入できる
+				l.ExprType = defaultType(rtypes[i])
+				continue
+			}
+			if ltype := traverse(l); !lang.AssignableTo(rtypes[i], ltype) {
+				util.Alarm("型%sの値を型%sの変数に代入することはできません", rtypes[i], ltype)
+			}
 		}
+		lhs.ExprType = stmtType
 
 		node.ExprType = stmtType
 		return stmtType
@@ -176,19 +189,30 @@ func traverse(node *parse.Node) lang.Type {
 			}
 		}
 		for i, l := range lhs.Children {
+			var ty = rhsType
 			if rhsType.Kind == lang.TypeMultiple {
-				l.Variable.Type = rhsType.Components[i]
-				l.ExprType = rhsType.Components[i]
-			} else {
-				l.Variable.Type = rhsType
-				l.ExprType = rhsType
+				ty = rhsType.Components[i]
+			}
+			if l.Redeclared || l.Variable.Type.Kind != lang.TypeUndefined {
+				// 再宣言された変数やvar文で型が指定された変数の型は変わらない
+				if !lang.AssignableTo(ty, l.Variable.Type) {
+					util.Alarm("型%sの変数%sに型%sの値を代入することはできません", l.Variable.Type, l.Variable.Name, ty)
+				}
+				l.ExprType = l.Variable.Type
+				continue
 			}
+			ty = defaultType(ty)
+			l.Variable.Type = ty
+			l.ExprType = ty
 		}
 
 		node.ExprType = stmtType
 		return stmtType
 	}
 	if node.Kind == parse.NodeMetaIf {
+		if node.Init != nil {
+			traverse(node.Init)
+		}
 		traverse(node.If)
 		if node.Else != nil {
 			traverse(node.Else)
@@ -199,7 +223,7 @@ func traverse(node *parse.Node) lang.Type {
 	if node.Kind == parse.NodeIf {
 		traverse(node.Condition)
 		traverse(node.Body)
-		if node.Condition.ExprType.Kind != lang.TypeBool {
+		if !lang.AllUnderlying(node.Condition.ExprType, isBool) {
 			util.Alarm("if文の条件として使える式はbool型のものだけです")
 		}
 		node.ExprType = stmtType
@@ -228,6 +252,9 @@ func traverse(node *parse.Node) lang.Type {
 		for _, param := range node.Parameters { // 引数
 			traverse(param)
 		}
+		for _, result := range node.Results { // 名前付きの返り値
+			traverse(result)
+		}
 		traverse(node.Body) // 関数本体
 		alignLocalVars(node.Env.FunctionName)
 		node.ExprType = stmtType
@@ -235,10 +262,10 @@ func traverse(node *parse.Node) lang.Type {
 	}
 	if node.Kind == parse.NodeNot {
 		var ty = traverse(node.Target)
-		if ty.Kind != lang.TypeBool {
-			panic("否定演算子の後に続くのはbool型の値だけです")
+		if !lang.AllUnderlying(ty, isBool) {
+			util.Alarm("否定演算子の後に続くのはbool型の値だけです")
 		}
-		node.ExprType = lang.NewType(lang.TypeBool)
+		node.ExprType = ty
 		return node.ExprType
 	}
 	if node.Kind == parse.NodeAddr {
@@ -247,7 +274,7 @@ func traverse(node *parse.Node) lang.Type {
 		return node.ExprType
 	}
 	if node.Kind == parse.NodeDeref {
-		var ty = traverse(node.Target)
+		var ty = lang.Underlying(traverse(node.Target))
 		if ty.Kind != lang.TypePtr {
 			util.Alarm("ポインタでないものの参照を外そうとしています")
 		}
@@ -256,8 +283,24 @@ func traverse(node *parse.Node) lang.Type {
 	}
 	if node.Kind == parse.NodeFunctionCall {
 		p := packageToProgram(node.In)
+		if p == program && p.FindFunction(node.Label) == nil && p.FindGenericFunction(node.Label) == nil {
+			// ドットインポートしたパッケージの関数
+			if dp := findDotImported(node, node.Label, func(q *parse.Program) bool {
+				return q.FindFunction(node.Label) != nil || q.FindGenericFunction(node.Label) != nil
+			}); dp != nil {
+				p = dp
+				node.In = dp.Name
+			}
+		}
 
-		fn := p.FindFunction(node.Label)
+		var fn *lang.Function
+		if g := p.FindGenericFunction(node.Label); g != nil {
+			fn = instantiateCall(node, p, g)
+		} else if len(node.TypeArguments) > 0 {
+			parse.BadToken(node.NameToken, "関数"+node.Label+"は型パラメータを持っていません")
+		} else {
+			fn = p.FindFunction(node.Label)
+		}
 		if fn == nil {
 			node.In = ""
 			for _, argument := range node.Arguments {
@@ -266,29 +309,80 @@ func traverse(node *parse.Node) lang.Type {
 			node.ExprType = lang.NewUndefinedType()
 			return node.ExprType
 		}
+		if node.HasEllipsis && !fn.IsVariadic {
+			util.Alarm("可変長引数を持たない関数%sに...で展開した引数を渡すことはできません", fn.Label)
+		}
+		if fn.IsVariadic && !node.HasEllipsis {
+			// 可変長引数に渡された値はスライスリテラルにまとめてから渡す
+			var fixed = len(fn.ParameterTypes) - 1
+			if len(node.Arguments) < fixed {
+				util.Alarm("関数%sの引数の数が正しくありません", fn.Label)
+			}
+			var rest = parse.NewSliceLiteral(fn.ParameterTypes[fixed], node.Arguments[fixed:])
+			rest.Env = node.Env
+			rest.In = node.In
+			node.Arguments = append(node.Arguments[:fixed:fixed], rest)
+		}
 		if len(fn.ParameterTypes) != len(node.Arguments) {
 			util.Alarm("関数%sの引数の数が正しくありません", fn.Label)
 		}
 		for i, argument := range node.Arguments {
-			if !lang.TypeCompatable(fn.ParameterTypes[i], traverse(argument)) {
-				util.Alarm("関数%sの%d番目の引数の型が一致しません", fn.Label, i)
+			if ty := traverse(argument); !lang.AssignableTo(ty, fn.ParameterTypes[i]) {
+				util.Alarm("関数%sの%d番目の引数に型%sの値を渡すことはできません(型%sが必要です)", fn.Label, i, ty, fn.ParameterTypes[i])
 			}
 		}
 		node.ExprType = fn.ReturnValueType
 		return node.ExprType
 	}
+	if node.Kind == parse.NodeTopLevelVarStmt && node.Children[0].Kind == parse.NodeExprList {
+		// 多値を返す関数の返り値でまとめて初期化する
+		var valueType = traverse(node.Children[1])
+		var vars = node.Children[0].Children
+		if valueType.Kind != lang.TypeMultiple || len(valueType.Components) != len(vars) {
+			util.Alarm("var文の変数の数は%dですが、初期化式の値の型は%sです", len(vars), valueType)
+		}
+		for i, v := range vars {
+			var ty = valueType.Components[i]
+			var varType lang.Type
+			if v.Kind == parse.NodeBlank {
+				if v.ExprType.Kind == lang.TypeUndefined {
+					v.ExprType = ty
+				}
+				varType = v.ExprType
+			} else {
+				if v.Variable.Type.Kind == lang.TypeUndefined {
+					v.Variable.Type = ty
+				}
+				varType = traverse(v)
+			}
+			if !lang.AssignableTo(ty, varType) {
+				util.Alarm("var文で型%sの変数を型%sの値で初期化することはできません", varType, ty)
+			}
+		}
+		node.Children[0].ExprType = stmtType
+		node.ExprType = stmtType
+		return stmtType
+	}
 	if node.Kind == parse.NodeLocalVarStmt || node.Kind == parse.NodeTopLevelVarStmt {
-		if len(node.Children) == 2 {
+		if len(node.Children) == 2 && node.Children[0].Kind == parse.NodeBlank {
+			var valueType = traverse(node.Children[1])
+			if node.Children[0].ExprType.Kind == lang.TypeUndefined {
+				node.Children[0].ExprType = defaultType(valueType)
+			}
+			if !lang.AssignableTo(valueType, node.Children[0].ExprType) {
+				util.Alarm("var文で型%sの変数を型%sの値で初期化することはできません", node.Children[0].ExprType, valueType)
+			}
+		} else if len(node.Children) == 2 {
 			var lvarType = traverse(node.Children[0])
 			var valueType = traverse(node.Children[1])
 
 			if lvarType.Kind == lang.TypeUndefined {
-				node.Children[0].Variable.Type = valueType
-				node.Children[0].ExprType = valueType
-				lvarType = valueType
+				lvarType = defaultType(valueType)
+				node.Children[0].Variable.Type = lvarType
+				node.Children[0].ExprType = lvarType
 			}
-			if !lang.TypeCompatable(lvarType, valueType) {
-				util.Alarm("var文における変数の型と初期化式の型が一致しません")
+			if !lang.AssignableTo(valueType, lvarType) {
+				util.Alarm("var文で型%sの変数を型%sの値で初期化することはできません", lvarType, valueType)
 			}
 		}
 		if node.Kind == parse.NodeLocalVarList {
@@ -302,12 +396,20 @@ func traverse(node *parse.Node) lang.Type {
 		node.ExprType = stmtType
 		return stmtType
 	}
+	if node.Kind == parse.NodeBlank {
+		util.Alarm("_は値として使用できません")
+	}
 	if node.Kind == parse.NodeNum {
-		node.ExprType = lang.NewType(lang.TypeInt)
+		// 文字リテラルの既定の型はrune、それ以外の整数リテラルはint
+		if node.LiteralType.Kind == lang.TypeRune {
+			node.ExprType = lang.NewUntypedType(lang.TypeRune)
+		} else {
+			node.ExprType = lang.NewUntypedType(lang.TypeInt)
+		}
 		return node.ExprType
 	}
 	if node.Kind == parse.NodeBool {
-		node.ExprType = lang.NewType(lang.TypeBool)
+		node.ExprType = lang.NewUntypedType(lang.TypeBool)
 		return node.ExprType
 	}
 	if node.Kind == parse.NodeLocalVariable {
@@ -315,7 +417,14 @@ func traverse(node *parse.Node) lang.Type {
 		return node.Variable.Type
 	}
 	if node.Kind == parse.NodeTopLevelVariable {
-		v := program.FindTopLevelVariable(node.Label)
+		v := packageToProgram(node.In).FindTopLevelVariable(node.Label)
+		if v == nil && node.In == program.Name {
+			// ドットインポートしたパッケージの変数
+			if p := findDotImported(node, node.Label, func(q *parse.Program) bool { return q.FindTopLevelVariable(node.Label) != nil }); p != nil {
+				node.In = p.Name
+				v = p.FindTopLevelVariable(node.Label)
+			}
+		}
 		if v == nil {
 			panic("トップレベル変数" + node.Label + "は未定義です")
 		}
@@ -324,7 +433,7 @@ func traverse(node *parse.Node) lang.Type {
 		return node.ExprType
 	}
 	if node.Kind == parse.NodeString {
-		node.ExprType = lang.NewType(lang.TypeString)
+		node.ExprType = lang.NewUntypedType(lang.TypeString)
 		return node.ExprType
 	}
 	if node.Kind == parse.NodeIndex {
@@ -333,7 +442,7 @@ func traverse(node *parse.Node) lang.Type {
 		if seqType.Kind != lang.TypeArray && seqType.Kind != lang.TypeSlice {
 			util.Alarm("配列でもスライスでもないものに添字でアクセスしようとしています")
 		}
-		if !lang.IsKindOfNumber(indexType) {
+		if !lang.AllUnderlying(indexType, lang.IsKindOfNumber) {
 			util.Alarm("配列の添字は整数でなくてはなりません")
 		}
 		node.ExprType = *seqType.PtrTo
@@ -341,13 +450,23 @@ func traverse(node *parse.Node) lang.Type {
 	}
 	if node.Kind == parse.NodeAppendCall {
 		var arg1Type = traverse(node.Arguments[0])
-		var arg2Type = traverse(node.Arguments[1])
 
 		if arg1Type.Kind != lang.TypeSlice {
-			panic("appendの第一引数はスライスでなくてはいけません")
+			util.Alarm("appendの第一引数はスライスでなくてはいけません")
 		}
-		if !lang.TypeEquals(*arg1Type.PtrTo, arg2Type) {
-			panic("第二引数の型は第一引数で指定されたスライスに追加できません")
+		if node.HasEllipsis {
+			if len(node.Arguments) != 2 {
+				util.Alarm("...で展開したスライスをappendする場合、引数は2つでなくてはいけません")
+			}
+			if !lang.TypeEquals(arg1Type, traverse(node.Arguments[1])) {
+				util.Alarm("第二引数のスライスは第一引数のスライスに追加できません")
+			}
+		} else {
+			for _, arg := range node.Arguments[1:] {
+				if ty := traverse(arg); !lang.AssignableTo(ty, *arg1Type.PtrTo) {
+					util.Alarm("型%sの値は型%sのスライスに追加できません", ty, arg1Type)
+				}
+			}
 		}
 		node.ExprType = arg1Type
 		return node.ExprType
@@ -357,27 +476,54 @@ func traverse(node *parse.Node) lang.Type {
 		node.ExprType = lang.NewType(lang.TypeString)
 		return node.ExprType
 	}
-	if node.Kind == parse.NodeRuneCall {
+	if node.Kind == parse.NodeConversion {
 		ty := traverse(node.Arguments[0])
-		if !lang.IsKindOfNumber(ty) {
-			panic("runeの引数は整数型でなくてはいけません")
+		if !lang.ConvertibleTo(ty, node.LiteralType) {
+			util.Alarm("型%sの値を型%sに変換することはできません", ty, node.LiteralType)
 		}
-		node.ExprType = lang.NewType(lang.TypeRune)
+		node.ExprType = node.LiteralType
+		return node.ExprType
+	}
+	if node.Kind == parse.NodeNil {
+		node.ExprType = lang.NewUntypedType(lang.TypeNil)
 		return node.ExprType
 	}
+	if node.Kind == parse.NodeNewCall {
+		var ty = node.LiteralType
+		node.ExprType = lang.NewPointerType(&ty)
+		return node.ExprType
+	}
+	if node.Kind == parse.NodeCompositeLiteral {
+		resolveCompositeLiteral(node)
+		return traverse(node)
+	}
 	if node.Kind == parse.NodeSliceLiteral {
 		node.ExprType = node.LiteralType
 		for _, c := range node.Children {
 			ty := traverse(c)
-			if !lang.TypeCompatable(*node.LiteralType.PtrTo, ty) {
-				panic("スライスの型と中身の要素の型が一致しません")
+			if !lang.AssignableTo(ty, *lang.Underlying(node.LiteralType).PtrTo) {
+				util.Alarm("型%sの値を型%sのスライスの要素にすることはできません", ty, node.LiteralType)
+			}
+		}
+		return node.LiteralType
+	}
+	if node.Kind == parse.NodeArrayLiteral {
+		node.ExprType = node.LiteralType
+		var arrayType = lang.Underlying(node.LiteralType)
+		if len(node.Children) > arrayType.ArraySize {
+			util.Alarm("型%sのリテラルの要素が多すぎます", node.LiteralType)
+		}
+		for _, c := range node.Children {
+			ty := traverse(c)
+			if !lang.AssignableTo(ty, *arrayType.PtrTo) {
+				util.Alarm("型%sの値を型%sの配列の要素にすることはできません", ty, node.LiteralType)
 			}
 		}
 		return node.LiteralType
 	}
 	if node.Kind == parse.NodeStructLiteral {
 		node.ExprType = node.LiteralType
-		entityType := *node.ExprType.PtrTo
+		entityType := lang.Underlying(node.ExprType)
 
 		for i := 0; i < len(node.MemberNames); i++ {
 			name := node.MemberNames[i]
@@ -388,8 +534,11 @@ func traverse(node *parse.Node) lang.Type {
 			for j := 0; j < len(entityType.MemberNames); j++ {
 				if entityType.MemberNames[j] == name {
 					found = true
-					if !lang.TypeCompatable(ty, entityType.MemberTypes[j]) {
-						panic(node.ExprType.DefinedName + "のメンバーの型と一致しません")
+					if !isAccessibleMember(node.ExprType, name) {
+						util.Alarm("cannot refer to unexported field %s in struct literal of type %s", name, node.ExprType)
+					}
+					if !lang.AssignableTo(ty, entityType.MemberTypes[j]) {
+						util.Alarm("型%sのメンバー%sに型%sの値を指定することはできません", node.ExprType, name, ty)
 					}
 				}
 			}
@@ -401,49 +550,168 @@ func traverse(node *parse.Node) lang.Type {
 	}
 	if node.Kind == parse.NodeDot {
 		ty := traverse(node.Owner)
-		if ty.Kind == lang.TypeUserDefined || (ty.Kind == lang.TypePtr && ty.PtrTo.Kind == lang.TypeUserDefined) {
-			entityType := *ty.PtrTo
-			if ty.Kind == lang.TypePtr {
-				entityType = *entityType.PtrTo
-			}
+		if entityType, ok := lang.StructOf(ty); ok {
 			for i := 0; i < len(entityType.MemberNames); i++ {
 				name := entityType.MemberNames[i]
 				if node.MemberName == name {
+					if !isAccessibleMember(ty, name) {
+						parse.BadToken(node.NameToken, "cannot refer to unexported field "+name+" of type "+ty.String())
+					}
 					node.ExprType = entityType.MemberTypes[i]
 					return node.ExprType
 				}
 			}
-			panic("型" + ty.DefinedName + "は" + node.MemberName + "という名前のメンバーを持ちません")
+			util.Alarm("型%sは%sという名前のメンバーを持ちません", ty, node.MemberName)
 		}
-		panic(".は現在ユーザ定義の型の値に対してのみ実装されています")
+		util.Alarm("型%sの値のメンバーを参照することはできません", ty)
 	}
 
 	var lhsType = traverse(node.Lhs)
 	var rhsType = traverse(node.Rhs)
-
-	if !lang.TypeCompatable(lhsType, rhsType) {
-		util.Alarm("[%s] 左辺と右辺の式の型が違います %s %s", node.Kind, lhsType.Kind, rhsType.Kind)
-	}
+	var ty = operandType(node.Kind, lhsType, rhsType)
+	var underlying = lang.Underlying(ty)
+	// 型パラメータの値の場合は、制約が並べているすべての型でできる演算だけを許す
+	var isNumberOrString = func(u lang.Type) bool { return lang.IsKindOfNumber(u) || u.Kind == lang.TypeString }
 
 	switch node.Kind {
 	case parse.NodeSub, parse.NodeMul, parse.NodeDiv, parse.NodeMod:
-		// 両辺がintであることを期待
-		if lhsType.Kind != lang.TypeInt {
-			util.Alarm(string(node.Kind) + "の両辺の値はint型の値でなくてはなりません")
+		// 両辺が整数であることを期待
+		if !lang.AllUnderlying(ty, lang.IsKindOfNumber) {
+			util.Alarm("%sの両辺の値は整数でなくてはなりませんが、型%sの値が渡されています", node.Kind, ty)
 		}
-		node.ExprType = lang.NewType(lang.TypeInt)
+		node.ExprType = ty
 	case parse.NodeAdd:
-		node.ExprType = lhsType
-	case parse.NodeEql, parse.NodeNotEql, parse.NodeLess, parse.NodeLessEql, parse.NodeGreater, parse.NodeGreaterEql:
-		node.ExprType = lang.NewType(lang.TypeBool)
+		if !lang.AllUnderlying(ty, isNumberOrString) {
+			util.Alarm("%sの両辺の値は整数か文字列でなくてはなりませんが、型%sの値が渡されています", node.Kind, ty)
+		}
+		node.ExprType = ty
+	case parse.NodeEql, parse.NodeNotEql:
+		if underlying.Kind == lang.TypeNil {
+			util.Alarm("nil同士を比較することはできません")
+		}
+		if underlying.Kind == lang.TypeSlice {
+			if node.Lhs.Kind != parse.NodeNil && node.Rhs.Kind != parse.NodeNil {
+				util.Alarm("スライスはnilとしか比較できません")
+			}
+		} else if !lang.IsComparable(ty) {
+			util.Alarm("型%sの値は比較できません", ty)
+		}
+		node.ExprType = lang.NewUntypedType(lang.TypeBool)
+	case parse.NodeLess, parse.NodeLessEql, parse.NodeGreater, parse.NodeGreaterEql:
+		if !lang.AllUnderlying(ty, isNumberOrString) {
+			util.Alarm("%sの両辺の値は整数か文字列でなくてはなりませんが、型%sの値が渡されています", node.Kind, ty)
+		}
+		node.ExprType = lang.NewUntypedType(lang.TypeBool)
 	case parse.NodeLogicalAnd, parse.NodeLogicalOr:
 		// 両辺がBoolであることを期待
-		if lhsType.Kind != lang.TypeBool {
-			util.Alarm("&&の両辺の値はbool型の値でなくてはなりません")
+		if !lang.AllUnderlying(ty, isBool) {
+			util.Alarm("%sの両辺の値はbool型の値でなくてはなりませんが、型%sの値が渡されています", node.Kind, ty)
 		}
-		node.ExprType = lang.NewType(lang.TypeBool)
+		node.ExprType = ty
 	default:
 		node.ExprType = stmtType
 	}
 	return node.ExprType
 }
+
+func isBool(u lang.Type) bool {
+	return u.Kind == lang.TypeBool
+}
+
+// 型のない定数を、変数の型として使う型に変換する
+func defaultType(ty lang.Type) lang.Type {
+	if ty.Kind == lang.TypeNil {
+		util.Alarm("nilから変数の型を決めることはできません")
+	}
+	return lang.DefaultType(ty)
+}
+
+// T{...}を型Tに応じたリテラルのノードに置き換える
+func resolveCompositeLiteral(node *parse.Node) {
+	var ty = lang.Underlying(node.LiteralType)
+	switch ty.Kind {
+	case lang.TypeStruct:
+		node.Kind = parse.NodeStructLiteral
+		var unnamed = 0
+		for _, name := range node.MemberNames {
+			if name == "" {
+				unnamed++
+			}
+		}
+		if unnamed == 0 {
+			return
+		}
+		for _, name := range ty.MemberNames {
+			if !isAccessibleMember(node.LiteralType, name) {
+				util.Alarm("implicit assignment to unexported field %s in struct literal of type %s", name, node.LiteralType)
+			}
+		}
+		// T{1, 2} のように名前を省略した場合は、すべてのメンバーの値を宣言の順に並べる
+		if unnamed != len(node.MemberNames) {
+			util.Alarm("型%sのリテラルで名前を指定した値と指定していない値が混ざっています", node.LiteralType)
+		}
+		if len(node.MemberValues) != len(ty.MemberNames) {
+			util.Alarm("型%sのリテラルには%d個の値が必要ですが、%d個の値が指定されています", node.LiteralType, len(ty.MemberNames), len(node.MemberValues))
+		}
+		node.MemberNames = append([]string{}, ty.MemberNames...)
+	case lang.TypeSlice, lang.TypeArray:
+		if ty.Kind == lang.TypeSlice {
+			node.Kind = parse.NodeSliceLiteral
+		} else {
+			node.Kind = parse.NodeArrayLiteral
+		}
+		for i, name := range node.MemberNames {
+			if name != "" {
+				util.Alarm("型%sのリテラルの要素に名前を付けることはできません", node.LiteralType)
+			}
+			node.MemberValues[i] = elideType(node.MemberValues[i], *ty.PtrTo)
+		}
+		node.Children = node.MemberValues
+		node.MemberNames, node.MemberValues = nil, nil
+	case lang.TypeUndefined:
+		util.Alarm("型を省略したリテラルを書けるのは配列やスライスの要素だけです")
+	default:
+		util.Alarm("型%sの値はリテラルで書くことはできません", node.LiteralType)
+	}
+}
+
+// 型を省略したリテラルの要素に要素の型を補う
+// 要素の型が*Tの場合は&T{...}として扱う
+func elideType(value *parse.Node, elemType lang.Type) *parse.Node {
+	if value.Kind != parse.NodeCompositeLiteral || value.LiteralType.Kind != lang.TypeUndefined {
+		return value
+	}
+	var u = lang.Underlying(elemType)
+	if u.Kind == lang.TypePtr {
+		value.LiteralType = *u.PtrTo
+		return &parse.Node{Kind: parse.NodeAddr, Target: value, Env: value.Env, In: value.In}
+	}
+	value.LiteralType = elemType
+	return value
+}
+
+// 二項演算の両辺の型から、演算を行う型を決める
+// 片方だけが型のない定数の場合は、もう片方の型に合わせる
+func operandType(kind parse.NodeKind, lhsType lang.Type, rhsType lang.Type) lang.Type {
+	if lhsType.Untyped && rhsType.Untyped {
+		if lhsType.Kind == rhsType.Kind {
+			return lhsType
+		}
+		if lang.IsKindOfNumber(lhsType) && lang.IsKindOfNumber(rhsType) {
+			// 整数と文字の定数の演算は文字の定数になる
+			return lang.NewUntypedType(lang.TypeRune)
+		}
+	} else if lhsType.Untyped {
+		if lang.AssignableTo(lhsType, rhsType) {
+			return rhsType
+		}
+	} else if rhsType.Untyped {
+		if lang.AssignableTo(rhsType, lhsType) {
+			return lhsType
+		}
+	} else if lang.TypeEquals(lhsType, rhsType) {
+		return lhsType
+	}
+	util.Alarm("[%s] 左辺の型%sと右辺の型%sが一致しません", kind, lhsType, rhsType)
+	return lhsType
+}
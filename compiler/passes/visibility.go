@@ -0,0 +1,22 @@
+package passes
+
+import (
+	"github.com/myuu222/myuugo/compiler/lang"
+	"github.com/myuu222/myuugo/compiler/util"
+)
+
+// 構造体のメンバーを宣言したパッケージを返す
+// 名前の付いた型ではなく、構造体の型が書かれたパッケージを返す。構造体でなければ空文字列を返す
+func memberPackageOf(ty lang.Type) string {
+	entityType, ok := lang.StructOf(ty)
+	if !ok {
+		return ""
+	}
+	return entityType.Package
+}
+
+// 型tyの構造体のメンバーnameを、いま調べているパッケージから参照できるかどうか
+func isAccessibleMember(ty lang.Type, name string) bool {
+	var pkg = memberPackageOf(ty)
+	return pkg == "" || pkg == program.Name || util.IsExported(name)
+}
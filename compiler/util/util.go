@@ -27,8 +27,23 @@ func EnumerateGoFilePaths(path string) []string {
 	return paths
 }
 
+// ディスク上にはないファイルの内容。コンパイラに埋め込まれた標準ライブラリのソースなどを置く
+var virtualFiles = map[string]string{}
+
+// pathのファイルの内容としてcontentを登録する。ReadFileはディスクより先に登録されたファイルを探すので、
+// pathにはディスク上のファイルのパスと重ならないもの("embed:"で始まるパスなど)を使う
+func AddVirtualFile(path string, content string) {
+	virtualFiles[path] = content
+}
+
 // ファイルの末尾に改行を付与して読み込む
 func ReadFile(path string) string {
+	if content, ok := virtualFiles[path]; ok {
+		if !strings.HasSuffix(content, "\n") {
+			content += "\n"
+		}
+		return content
+	}
 	bytes, err := ioutil.ReadFile(path)
 	if err != nil {
 		fmt.Println(path)
@@ -40,14 +55,8 @@ func ReadFile(path string) string {
 	return string(bytes)
 }
 
-// エラーの起きた場所を報告するための関数
-// 下のようなフォーマットでエラーメッセージを表示する
-//
-// foo.c:10: x = y + + 5;
-//                   ^ 式ではありません
-func ErrorAt(path string, target string, message string) {
-	var content = ReadFile(path)
-	// 行番号と、restがその行の何番目から始まるかを見つける
+// ファイルの内容contentのうち、末尾の部分文字列targetが始まる行番号と、その行の何文字目から始まるかを返す
+func locate(content string, target string) (int, int) {
 	var lineNumber = 1
 	var startIndex = 0
 	for _, c := range content[:len(content)-len(target)] {
@@ -61,6 +70,24 @@ func ErrorAt(path string, target string, message string) {
 			startIndex += 1
 		}
 	}
+	return lineNumber, startIndex
+}
+
+// ファイルpathの末尾の部分文字列targetの位置を「ファイル名:行番号」の形で返す
+func Position(path string, target string) string {
+	var lineNumber, _ = locate(ReadFile(path), target)
+	return fmt.Sprintf("%s:%d", path, lineNumber)
+}
+
+// エラーの起きた場所を報告するための関数
+// 下のようなフォーマットでエラーメッセージを表示する
+//
+// foo.c:10: x = y + + 5;
+//                   ^ 式ではありません
+func ErrorAt(path string, target string, message string) {
+	var content = ReadFile(path)
+	// 行番号と、restがその行の何番目から始まるかを見つける
+	var lineNumber, startIndex = locate(content, target)
 	for i, line := range strings.Split(content, "\n") {
 		if i+1 == lineNumber {
 			// 見つかった行をファイル名と行番号と一緒に表示
@@ -95,6 +122,11 @@ func RuneAt(s string, i int) rune {
 	return []rune(s)[i]
 }
 
+// 他のパッケージから参照できる名前かどうか
+func IsExported(name string) bool {
+	return unicode.IsUpper(RuneAt(name, 0))
+}
+
 func Alarm(format string, args ...interface{}) {
 	fmt.Fprintf(os.Stderr, format, args...)
 	fmt.Fprintln(os.Stderr, "")
@@ -1,5 +1,13 @@
 package fmt
 
-func Println(arg string) {
-	puts(arg)
+// 引数を空白区切りで連結し、末尾に改行を付けて出力する
+func Println(args ...string) {
+	var line = ""
+	for i := 0; i < len(args); i = i + 1 {
+		if i > 0 {
+			line = line + " "
+		}
+		line = line + args[i]
+	}
+	puts(line)
 }
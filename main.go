@@ -1,7 +1,11 @@
 package main
 
 import (
+	"bufio"
+	"embed"
+	"flag"
 	"fmt"
+	"io/fs"
 	"os"
 
 	"github.com/myuu222/myuugo/compiler/codegen"
@@ -9,17 +13,97 @@ import (
 	"github.com/myuu222/myuugo/compiler/passes"
 )
 
+// コンパイラに埋め込む標準ライブラリのソース
+//
+//go:embed library
+var embeddedLibrary embed.FS
+
+var libraryDir = flag.String("library", "", "標準ライブラリのソースが置かれたディレクトリ。指定がなければ環境変数MYUUGO_LIBRARY、それもなければコンパイラに埋め込まれたソースを使う")
+
 func usage() {
-	fmt.Fprintln(os.Stderr, "引数の個数が正しくありません")
+	fmt.Fprintln(os.Stderr, "使い方: myuugo [-library ディレクトリ] <ディレクトリ>")
+	fmt.Fprintln(os.Stderr, "        myuugo [-library ディレクトリ] list [ディレクトリ]")
+	fmt.Fprintln(os.Stderr, "        myuugo demangle [シンボル...]")
+	flag.PrintDefaults()
 	os.Exit(1)
 }
 
+// 標準ライブラリのソースの場所を決める
+func setupLibrary() {
+	if *libraryDir != "" {
+		parse.SetLibraryDir(*libraryDir)
+		return
+	}
+	if dir := os.Getenv("MYUUGO_LIBRARY"); dir != "" {
+		parse.SetLibraryDir(dir)
+		return
+	}
+	library, err := fs.Sub(embeddedLibrary, "library")
+	if err != nil {
+		panic(err)
+	}
+	parse.SetLibraryFS(library, "library")
+}
+
+// 引数で渡されたシンボルを元の名前に戻して表示する
+// 引数がなければ、標準入力の各行に含まれるシンボルを元の名前に戻して表示する
+func demangle(symbols []string) {
+	if len(symbols) > 0 {
+		for _, symbol := range symbols {
+			fmt.Println(codegen.DemangleText(symbol))
+		}
+		return
+	}
+	var scanner = bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		fmt.Println(codegen.DemangleText(scanner.Text()))
+	}
+}
+
+// ディレクトリpathのパッケージと、そこからインポートされているパッケージについて、ビルドの対象になるファイルを表示する
+// ビルドの条件に合わずに除いたファイルは"(除外)"を付けて表示する
+func list(path string) {
+	setupLibrary()
+	for _, program := range parse.DependencyOrder(parse.Load(path)) {
+		if program.IsLibrary {
+			fmt.Println(program.Name, "(標準ライブラリ)")
+		} else {
+			fmt.Println(program.Name)
+		}
+		for _, source := range program.Sources {
+			fmt.Println("\t" + source.FileName)
+		}
+		for _, fileName := range program.IgnoredFiles {
+			fmt.Println("\t" + fileName + " (除外)")
+		}
+	}
+}
+
 func main() {
-	if len(os.Args) != 2 {
+	flag.Usage = usage
+	flag.Parse()
+	var args = flag.Args()
+	if len(args) >= 1 && args[0] == "demangle" {
+		demangle(args[1:])
+		return
+	}
+	if len(args) >= 1 && args[0] == "list" {
+		switch len(args) {
+		case 1:
+			list(".")
+		case 2:
+			list(args[1])
+		default:
+			usage()
+		}
+		return
+	}
+	if len(args) != 1 {
 		usage()
 	}
+	setupLibrary()
 
-	var path = os.Args[1]
+	var path = args[0]
 	var programs = parse.Parse(path)
 
 	passes.Semantic(programs)
@@ -1,16 +1,54 @@
 #!/bin/bash
 
+root=$(pwd)
+
+# ディレクトリ$2でコンパイラを実行し、できたプログラムの終了コードが$1になることを確かめる
+# 3番目以降の引数はそのままコンパイラに渡す
+assert_in() {
+  expected="$1"
+  dir="$2"
+  shift 2
+
+  (cd "$dir" && "$root/main" "$@") > tmp.s
+  gcc -no-pie -o tmp tmp.s
+  actual=0
+  ./tmp || actual="$?"
+
+  if [ "$actual" = "$expected" ]; then
+    echo "$dir: $* => $actual"
+  else
+    echo "$dir: $* => $expected expected, but got $actual"
+    exit 1
+  fi
+}
+
 assert() {
+  assert_in "$1" . "$2"
+}
+
+# ディレクトリ$2のコンパイルが失敗し、エラーメッセージに$1が含まれることを確かめる
+assert_error() {
+  expected="$1"
+  dir="$2"
+
+  if ./main "$dir" > tmp.s 2> tmp.err; then
+    echo "$dir => compile error expected, but compiled"
+    exit 1
+  fi
+  if grep -qF "$expected" tmp.err; then
+    echo "$dir => $expected"
+  else
+    echo "$dir => \"$expected\" expected, but got:"
+    cat tmp.err
+    exit 1
+  fi
+}
+
+assert_demangle() {
   expected="$1"
   input="$2"
 
-  ./main "library/fmt/" > tmp_fmt.s
-  ./main "library/os/" > tmp_os.s
-  ./main "library/strconv/" > tmp_strconv.s
-  ./main "$input" > tmp.s
-  gcc -no-pie -o tmp tmp.s tmp_fmt.s tmp_os.s tmp_strconv.s
-  ./tmp
-  actual="$?"
+  actual=$(./main demangle "$input")
 
   if [ "$actual" = "$expected" ]; then
     echo "$input => $actual"
@@ -20,4 +58,65 @@ assert() {
   fi
 }
 
+# myuugo listの出力に、行lineがexpected回現れるかどうか
+assert_list() {
+  expected="$1"
+  dir="$2"
+  line="$3"
+
+  actual=$(./main list "$dir" | grep -cxF "$line" || true)
+
+  if [ "$actual" = "$expected" ]; then
+    echo "list $dir: \"$line\" appears $actual time(s)"
+  else
+    echo "list $dir: \"$line\" should appear $expected time(s), but appeared $actual time(s)"
+    exit 1
+  fi
+}
+
 assert 0 "tests/"
+assert 37 "tests/monorepo/app/"
+
+# 標準ライブラリはコンパイラに埋め込まれているので、どのディレクトリからでもコンパイルできる
+assert_in 37 tests/monorepo/app ./
+assert_in 37 / -library "$root/library" "$root/tests/monorepo/app/"
+MYUUGO_LIBRARY="$root/library" assert_in 37 / "$root/tests/monorepo/app/"
+
+# replaceの置き換え先が絶対パスの場合
+absapp=$(mktemp -d)
+cp tests/monorepo/app/main.go "$absapp/"
+sed "s|=> ../lib|=> $(pwd)/tests/monorepo/lib|" tests/monorepo/app/go.mod > "$absapp/go.mod"
+assert 37 "$absapp/"
+rm -rf "$absapp"
+
+assert_error "型Celsiusの値を型Fahrenheitの変数に代入することはできません" tests/errors/namedtype/
+assert_error "スライスはnilとしか比較できません" tests/errors/slicecompare/
+assert_error "import cycle not allowed" tests/errors/cycle/
+assert_error '"github.com/myuu222/myuugo/tests/mathutil" imported and not used' tests/errors/unusedimport/
+assert_error "mathutilは既にインポートされています" tests/errors/duplicateimport/
+assert_error "LESSの両辺の値は整数か文字列でなくてはなりませんが、型Tの値が渡されています" tests/errors/genericops/
+assert_error "[ADD] 左辺の型Tと右辺の型untyped stringが一致しません" tests/errors/genericbody/
+assert_error "undefined: g.Nope" tests/errors/undefined/
+assert_error "cannot refer to unexported name geometry.abs" tests/errors/unexported/
+assert_error "cannot refer to unexported field count" tests/errors/field/
+assert_error "cannot refer to unexported field text" tests/errors/unnamedfield/
+
+# 埋め込まれた標準ライブラリのファイルは、ディスク上のファイルと区別できる名前で表す
+assert_list 1 tests/ "	embed:library/fmt/fmt.go"
+assert_list 0 tests/ "	library/fmt/fmt.go"
+
+assert_list 1 tests/platform/ "	tests/platform/os_linux.go"
+assert_list 1 tests/platform/ "	tests/platform/nocgo.go"
+assert_list 1 tests/platform/ "	tests/platform/os_windows.go (除外)"
+assert_list 1 tests/platform/ "	tests/platform/arch_arm64.go (除外)"
+assert_list 1 tests/platform/ "	tests/platform/cgo.go (除外)"
+assert_list 1 tests/platform/ "	tests/platform/gen.go (除外)"
+assert_list 1 tests/platform/ "	tests/platform/x_linux_amd64.go"
+assert_list 1 tests/platform/ "	tests/platform/x_windows_amd64.go (除外)"
+assert_list 1 tests/platform/ "	tests/platform/platform_test.go (除外)"
+
+assert_demangle "a_b/c.F" "go.a__b_2fc.F"
+assert_demangle "a/b_c.F" "go.a_2fb__c.F"
+assert_demangle "example.com/geo.Max[example.com/geo.Meter]" "go.example_2ecom_2fgeo.Max_5bexample_2ecom_2fgeo_2eMeter_5d"
+assert_demangle "main.init.0" "go.main.init_2e0"
+assert_demangle "puts" "puts"
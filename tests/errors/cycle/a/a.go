@@ -0,0 +1,7 @@
+package a
+
+import "github.com/myuu222/myuugo/tests/errors/cycle/b"
+
+func F() int {
+	return b.G()
+}
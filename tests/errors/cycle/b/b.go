@@ -0,0 +1,7 @@
+package b
+
+import "github.com/myuu222/myuugo/tests/errors/cycle/a"
+
+func G() int {
+	return a.F()
+}
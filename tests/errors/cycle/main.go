@@ -0,0 +1,7 @@
+package main
+
+import "github.com/myuu222/myuugo/tests/errors/cycle/a"
+
+func main() {
+	a.F()
+}
@@ -0,0 +1,10 @@
+package main
+
+import (
+	"github.com/myuu222/myuugo/tests/mathutil"
+	mathutil "github.com/myuu222/myuugo/tests/mathutil/digits"
+)
+
+func main() {
+	mathutil.Count(10)
+}
@@ -0,0 +1,8 @@
+package main
+
+import "github.com/myuu222/myuugo/tests/geometry"
+
+func main() {
+	var c geometry.Counter
+	c.count = 1
+}
@@ -0,0 +1,9 @@
+package main
+
+// 呼び出されていなくても本体を検査する
+func Bad[T any](a T) T {
+	return a + "x"
+}
+
+func main() {
+}
@@ -0,0 +1,9 @@
+package main
+
+func Lt[T any](a T, b T) bool {
+	return a < b
+}
+
+func main() {
+	Lt(1, 2)
+}
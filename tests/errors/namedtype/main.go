@@ -0,0 +1,12 @@
+package main
+
+type Celsius int
+type Fahrenheit int
+
+func main() {
+	var c Celsius = 100
+	var f Fahrenheit
+	// 元になる型が同じでも、名前の付いた型どうしは代入できない
+	f = c
+	f = f + 1
+}
@@ -0,0 +1,9 @@
+package main
+
+func main() {
+	var a = []int{1, 2}
+	var b = []int{1, 2}
+	if a == b {
+		a = b
+	}
+}
@@ -0,0 +1,7 @@
+package main
+
+import g "github.com/myuu222/myuugo/tests/geometry"
+
+func main() {
+	g.Nope()
+}
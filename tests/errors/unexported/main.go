@@ -0,0 +1,7 @@
+package main
+
+import "github.com/myuu222/myuugo/tests/geometry"
+
+func main() {
+	geometry.abs(1)
+}
@@ -0,0 +1,6 @@
+package main
+
+import "github.com/myuu222/myuugo/tests/mathutil"
+
+func main() {
+}
@@ -0,0 +1,70 @@
+package geometry
+
+type Point struct {
+	X int
+	Y int
+}
+
+type Segment struct {
+	From Point
+	To   Point
+}
+
+type Pair[T any] struct {
+	First  T
+	Second T
+}
+
+type Meter int
+
+// countはパッケージの外からは直接変更できない
+type Counter struct {
+	Name  string
+	count int
+}
+
+// 名前の付いていない構造体の型でも、textはパッケージの外からは参照できない
+type Label = struct {
+	text string
+}
+
+var Origin = Point{0, 0}
+var Untitled Label
+var Created int
+
+func NewPoint(x int, y int) *Point {
+	Created = Created + 1
+	return &Point{x, y}
+}
+
+func Length(s Segment) Meter {
+	return Meter(abs(s.To.X-s.From.X) + abs(s.To.Y-s.From.Y))
+}
+
+// 原点からの距離が遠い方を返す
+func Farther[T ~int](a T, b T) T {
+	Created = Created + 1
+	if abs(int(a)) < abs(int(b)) {
+		return b
+	}
+	return a
+}
+
+func MakePair[T any](first T, second T) Pair[T] {
+	return Pair[T]{First: first, Second: second}
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func Increment(c *Counter) {
+	c.count = c.count + 1
+}
+
+func Count(c Counter) int {
+	return c.count
+}
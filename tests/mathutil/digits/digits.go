@@ -0,0 +1,42 @@
+package digits
+
+var Base = 10
+
+// 下の桁から順に並べた各桁の数字
+type Digits struct {
+	Values []int
+}
+
+type Window[T any] struct {
+	Low  T
+	High T
+}
+
+func DigitSum(x int) int {
+	var sum = 0
+	for x > 0 {
+		sum = sum + x%Base
+		x = x / Base
+	}
+	return sum
+}
+
+func Count(x int) int {
+	if x < 10 {
+		return 1
+	}
+	return 1 + Count(x/10)
+}
+
+func Split(x int) Digits {
+	var d = Digits{}
+	for x > 0 {
+		d.Values = append(d.Values, x%Base)
+		x = x / Base
+	}
+	return d
+}
+
+func Last[T any](xs []T) T {
+	return xs[len(xs)-1]
+}
@@ -0,0 +1,41 @@
+package mathutil
+
+import "github.com/myuu222/myuugo/tests/mathutil/digits"
+
+var initialized int
+var registered int
+
+func init() {
+	initialized = 1
+}
+
+func Initialized() int {
+	return initialized
+}
+
+func Register(n int) {
+	registered = registered + n
+}
+
+func Registered() int {
+	return registered
+}
+
+func Abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func Gcd(a int, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return Abs(a)
+}
+
+// 各桁の和が割り切れるかどうかを使って3の倍数かどうかを調べる
+func IsMultipleOfThree(x int) bool {
+	return digits.DigitSum(Abs(x))%3 == 0
+}
@@ -0,0 +1,10 @@
+// 兄弟のディレクトリにあるモジュールをreplaceで参照するモジュール
+module example.com/monorepo/app
+
+go 1.16
+
+require (
+	example.com/monorepo/lib v0.0.0 // ネットワークからは取得しない
+)
+
+replace example.com/monorepo/lib => ../lib
@@ -0,0 +1,12 @@
+package main
+
+import (
+	"os"
+
+	"example.com/monorepo/lib"
+	"example.com/monorepo/lib/shapes"
+)
+
+func main() {
+	os.Exit(lib.Area(3, 4) + shapes.Square(5))
+}
@@ -0,0 +1,7 @@
+package lib
+
+import "example.com/monorepo/lib/shapes"
+
+func Area(width int, height int) int {
+	return shapes.Rectangle(width, height)
+}
@@ -0,0 +1,9 @@
+package shapes
+
+func Rectangle(width int, height int) int {
+	return width * height
+}
+
+func Square(side int) int {
+	return Rectangle(side, side)
+}
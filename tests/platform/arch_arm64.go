@@ -0,0 +1,5 @@
+package platform
+
+func archCode() int {
+	return 4
+}
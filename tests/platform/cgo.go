@@ -0,0 +1,7 @@
+//go:build !linux || cgo
+
+package platform
+
+func cgoCode() int {
+	return 6
+}
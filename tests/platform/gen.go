@@ -0,0 +1,7 @@
+//go:build ignore
+
+// パッケージのファイルを生成するためのプログラム。ビルドの対象にはならない
+package main
+
+func main() {
+}
@@ -0,0 +1,5 @@
+package platform
+
+func osCode() int {
+	return 2
+}
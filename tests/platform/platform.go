@@ -0,0 +1,6 @@
+package platform
+
+// OS、アーキテクチャ、cgoの有無に応じて別々のファイルから選ばれた関数の結果をまとめる
+func Describe() int {
+	return osCode()*1000 + archCode()*100 + cgoCode()*10 + pairCode()
+}
@@ -0,0 +1,10 @@
+package platform
+
+import "testing"
+
+// テストのファイルはビルドの対象にならない
+func TestDescribe(t *testing.T) {
+	if Describe() == 0 {
+		t.Fail()
+	}
+}
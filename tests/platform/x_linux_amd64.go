@@ -0,0 +1,6 @@
+package platform
+
+// 名前の末尾にOSとアーキテクチャの両方が書かれたファイルは、どちらも合う場合にだけ選ばれる
+func pairCode() int {
+	return 7
+}
@@ -0,0 +1,5 @@
+package platform
+
+func pairCode() int {
+	return 8
+}
@@ -0,0 +1,8 @@
+package registrar
+
+import "github.com/myuu222/myuugo/tests/mathutil"
+
+// インポートされただけで登録される
+func init() {
+	mathutil.Register(10)
+}
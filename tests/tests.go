@@ -3,6 +3,9 @@ package main
 import (
 	"fmt"
 	"strconv"
+
+	"github.com/myuu222/myuugo/tests/mathutil"
+	"github.com/myuu222/myuugo/tests/mathutil/digits"
 )
 
 func main() {
@@ -71,7 +74,7 @@ func main() {
 	// testInt("array test 4", 12, arrayTest4())
 	testInt("array test 5", 22, arrayTest5())
 
-	testInt("rune test 1", 91, runeTest1())
+	testInt("rune test 1", 91, int(runeTest1()))
 	testInt("rune test 2", 3, runeTest2())
 	testInt("rune test 3", 2, runeTest3())
 	testBool("rune test 4", true, runeTest4())
@@ -107,6 +110,102 @@ func main() {
 
 	testInt("len test 1", 7, lenTest1())
 
+	testInt("variadic test 1", 0, variadicTest1())
+	testInt("variadic test 2", 10, variadicTest2())
+	testInt("variadic test 3", 6, variadicTest3())
+	testInt("variadic test 4", 5, variadicTest4())
+	testInt("variadic test 5", 42, variadicTest5())
+
+	testInt("named result test 1", 0, namedResultTest1())
+	testInt("named result test 2", 7, namedResultTest2())
+	testInt("named result test 3", 13, namedResultTest3())
+	testInt("named result test 4", 30, namedResultTest4())
+
+	testInt("calling convention test 1", 36, callingConventionTest1())
+	testInt("calling convention test 2", 321, callingConventionTest2())
+	testInt("calling convention test 3", 1234, callingConventionTest3())
+	testInt("calling convention test 4", 100, callingConventionTest4())
+	testInt("calling convention test 5", 9, callingConventionTest5())
+
+	testInt("package init test 1", 23, derived)
+	testInt("package init test 2", 6, len(greeting)+groupedB)
+	testInt("package init test 3", 111, initCount)
+
+	testInt("if init test 1", 11, ifInitTest1())
+	testInt("if init test 2", 3, ifInitTest2())
+	testInt("if init test 3", 100, ifInitTest3())
+	testInt("switch test 1", 3, switchTest1(3))
+	testInt("switch test 2", 12, switchTest1(2))
+	testInt("switch test 3", 0, switchTest1(7))
+	testInt("switch test 4", -1, switchTest2(-5))
+	testInt("switch test 5", 0, switchTest2(0))
+	testInt("switch test 6", 1, switchTest2(9))
+	testInt("switch test 7", 42, switchTest3())
+
+	testInt("tuple assignment test 1", 21, tupleAssignmentTest1())
+	testInt("tuple assignment test 2", 312, tupleAssignmentTest2())
+	testInt("tuple assignment test 3", 16, tupleAssignmentTest3())
+	testInt("tuple assignment test 4", 21, tupleAssignmentTest4())
+	testInt("blank identifier test 1", 3, blankIdentifierTest1())
+	testInt("blank identifier test 2", 7, blankIdentifierTest2(5, 2))
+	testInt("blank identifier test 3", 1, blankSideEffect)
+
+	testInt("redeclaration test 1", 23, redeclarationTest1())
+	testInt("redeclaration test 2", 5, redeclarationTest2())
+
+	testInt("named type test 1", 12, namedTypeTest1())
+	testInt("named type test 2", 246, namedTypeTest2())
+	testInt("named type test 3", 2, namedTypeTest3())
+	testInt("named type test 4", 73, namedTypeTest4())
+
+	testInt("recursive type test 1", 6, recursiveTypeTest1())
+	testInt("recursive type test 2", 21, recursiveTypeTest2())
+	testInt("forward type test 1", 35, forwardTypeTest1())
+
+	testInt("grouped declaration test 1", 40, groupedDeclarationTest1())
+	testInt("grouped declaration test 2", 69, groupedDeclarationTest2())
+	testInt("grouped declaration test 3", 23, groupedDeclarationTest3())
+
+	testInt("zero value test 1", 5, zeroValueTest1())
+	testInt("zero value test 2", 11, zeroValueTest2())
+	testInt("zero value test 3", 7, zeroValueTest3())
+	testInt("nil test 1", 3, nilTest1())
+	testInt("nil test 2", 6, nilTest2())
+	testInt("new test 1", 12, newTest1())
+	testInt("composite literal test 1", 30, compositeLiteralTest1())
+	testInt("composite literal test 2", 60, compositeLiteralTest2())
+	testInt("composite literal test 3", 21, compositeLiteralTest3())
+	testInt("composite literal test 4", 9, compositeLiteralTest4())
+	testInt("equality test 1", 7, equalityTest1())
+	testInt("equality test 2", 15, equalityTest2())
+	testInt("equality test 3", 3, equalityTest3())
+	testInt("assignment copy test 1", 123, assignmentCopyTest1())
+	testInt("literal copy test 1", 123, literalCopyTest1())
+	testInt("generics test 1", 113, genericsTest1())
+	testInt("generics test 2", 17, genericsTest2())
+	testInt("generics test 3", 28, genericsTest3())
+	testInt("generics test 4", 15, genericsTest4())
+	testInt("generics test 5", 42, genericsTest5())
+	testInt("type alias test 1", 22, typeAliasTest1())
+	testInt("type alias test 2", 12, typeAliasTest2())
+	testInt("local type test 1", 9, localTypeTest1())
+	testInt("local type test 2", 16, localTypeTest2())
+	testInt("package test 1", 6, mathutil.Gcd(-12, 18))
+	testInt("package test 2", 4, digits.Count(mathutil.Abs(-2021)))
+	testBool("package test 3", true, mathutil.IsMultipleOfThree(-471))
+	testInt("package test 4", 1, mathutil.Initialized())
+	testInt("import test 1", 8, importTest1())
+	testInt("import test 2", 22, importTest2())
+	testInt("import test 3", 10, importTest3())
+	testInt("import name test 1", 8, importNameTest1())
+	testInt("dot import type test 1", 324, dotImportTypeTest1())
+	testInt("cross package generics test 1", 9455, crossPackageGenericsTest1())
+	testInt("cross package test 1", 8, crossPackageTest1())
+	testInt("cross package test 2", 2112, crossPackageTest2())
+	testInt("cross package test 3", 26, crossPackageTest3())
+	testInt("visibility test 1", 26, visibilityTest1())
+	testInt("build constraint test 1", 1357, buildConstraintTest1())
+
 	fmt.Println("OK")
 }
 
@@ -497,3 +596,851 @@ func lenTest1() int {
 	var z [2]int
 	return len(x) + len(y) + len(z)
 }
+
+func sum(xs ...int) int {
+	var total = 0
+	for i := 0; i < len(xs); i = i + 1 {
+		total = total + xs[i]
+	}
+	return total
+}
+
+func variadicTest1() int {
+	return sum()
+}
+
+func variadicTest2() int {
+	return sum(1, 2, 3, 4)
+}
+
+func variadicTest3() int {
+	var xs = []int{1, 2, 3}
+	return sum(xs...)
+}
+
+func variadicTest4() int {
+	var a = []int{1, 2}
+	a = append(a, 3, 4, 5)
+	fmt.Println("variadic", "test", "4")
+	return len(a)
+}
+
+func scale(factor int, xs ...int) int {
+	return factor * sum(xs...)
+}
+
+func variadicTest5() int {
+	var a = []int{1}
+	var b = []int{2, 3}
+	a = append(a, b...)
+	if len(a) != 3 || a[0] != 1 || a[2] != 3 {
+		return 0
+	}
+	return scale(7, a...)
+}
+
+func zeroResult() (n int) {
+	return
+}
+
+func namedResultTest1() int {
+	return zeroResult()
+}
+
+func divmod(a, b int) (q, r int) {
+	q = a / b
+	r = a % b
+	return
+}
+
+func namedResultTest2() int {
+	q, r := divmod(23, 5)
+	return q + r
+}
+
+func firstPositive(xs []int) (index int, found bool) {
+	for i := 0; i < len(xs); i = i + 1 {
+		if xs[i] > 0 {
+			return i, true
+		}
+	}
+	index = -1
+	return
+}
+
+func namedResultTest3() int {
+	i, ok := firstPositive([]int{-3, 0, 4})
+	j, ng := firstPositive([]int{-1})
+	if !ok || ng {
+		return 0
+	}
+	return i*5 + j*-3 + 0
+}
+
+func namedResultTest4() int {
+	var total = 0
+	for n := 1; n <= 4; n = n + 1 {
+		s, _ := divmod(n*15, 3)
+		total = total + s - n*2
+	}
+	return total
+}
+
+func sum8(a int, b int, c int, d int, e int, f int, g int, h int) int {
+	return a + b + c + d + e + f + g + h
+}
+
+func callingConventionTest1() int {
+	return sum8(1, 2, 3, 4, 5, 6, 7, 8)
+}
+
+func digits3(a int, b int, c int) (int, int, int) {
+	return c, b, a
+}
+
+func callingConventionTest2() int {
+	x, y, z := digits3(1, 2, 3)
+	return x*100 + y*10 + z
+}
+
+func many(a int, b int, c int, d int, e int, f int, g rune, h bool) (int, int, int, int) {
+	if !h {
+		return 0, 0, 0, 0
+	}
+	var z = 0
+	if g == 'a' {
+		z = 4
+	}
+	return a + b + c - 5, d + e - 7, f, z
+}
+
+func callingConventionTest3() int {
+	w, x, y, z := many(1, 2, 3, 4, 5, 3, 'a', true)
+	return w*1000 + x*100 + y*10 + z
+}
+
+type Counter struct {
+	Count int
+	Inner Streamer
+}
+
+func bump(c Counter) Counter {
+	c.Count = c.Count + 1
+	c.Inner.Power = c.Inner.Power + 1
+	return c
+}
+
+func callingConventionTest4() int {
+	var c Counter = Counter{Count: 99, Inner: Streamer{Name: "a", Power: 10}}
+	var d = bump(c)
+	if c.Count != 99 || c.Inner.Power != 10 {
+		return 0
+	}
+	return d.Count + d.Inner.Power - 11
+}
+
+func callingConventionTest5() int {
+	a, b, c := digits3(sum8(1, 1, 1, 1, 1, 1, 1, 1), 2, 3)
+	return a + b + c - 6 + 2
+}
+
+var derived = base*2 + lateOffset()
+
+func lateOffset() int {
+	return late
+}
+
+var base = 10
+var late = 3
+
+var (
+	groupedA int
+	groupedB = groupedA + 1
+
+	greeting string = "hello"
+)
+
+var initCount int
+
+func init() {
+	initCount = initCount + 1
+}
+
+func init() {
+	initCount = initCount + 10
+}
+
+func lookup(k int) (int, bool) {
+	if k == 1 {
+		return 10, true
+	}
+	return 0, false
+}
+
+func ifInitTest1() int {
+	var v = 5
+	if v, ok := lookup(1); ok {
+		v = v + 1
+		return v
+	}
+	return v
+}
+
+func ifInitTest2() int {
+	if v, ok := lookup(2); ok {
+		return v
+	} else if w := v + 3; w > 2 {
+		return w
+	} else {
+		return v
+	}
+}
+
+func ifInitTest3() int {
+	var v = 100
+	if v := 1; v > 0 {
+		v = v + 1
+	}
+	return v
+}
+
+func switchTest1(n int) int {
+	switch x := n; x {
+	case 1, 2:
+		return 12
+	case 3:
+		return x
+	default:
+		return 0
+	}
+}
+
+func switchTest2(n int) int {
+	var sign int
+	switch {
+	case n < 0:
+		sign = -1
+	default:
+		sign = 1
+	case n == 0:
+		sign = 0
+	}
+	return sign
+}
+
+func switchTest3() int {
+	var total = 0
+	for i := 0; i < 3; i = i + 1 {
+		switch i {
+		case 0:
+			total = total + 2
+		case 1:
+			switch j := i * 10; j {
+			case 10:
+				total = total + 30
+			}
+		default:
+			total = total + 10
+		}
+	}
+	return total
+}
+
+func tupleAssignmentTest1() int {
+	a, b := 1, 2
+	a, b = b, a
+	return a*10 + b
+}
+
+func tupleAssignmentTest2() int {
+	var xs = []int{1, 2, 3}
+	xs[0], xs[1], xs[2] = xs[2], xs[0], xs[1]
+	return xs[0]*100 + xs[1]*10 + xs[2]
+}
+
+func tupleAssignmentTest3() int {
+	var xs = []int{5, 6}
+	var i = 0
+	// 左辺の添字は代入より前に評価される
+	i, xs[i] = 1, 7
+	return i*10 + xs[0] - xs[1] + 5
+}
+
+func tupleAssignmentTest4() int {
+	var p = Streamer{Name: "a", Power: 1}
+	var q = Streamer{Name: "bb", Power: 3}
+	p, q = q, p
+	p.Power = p.Power + len(p.Name)
+	return p.Power*10 - q.Power*10 + len(q.Name) - len(p.Name) - 18
+}
+
+var blankSideEffect int
+
+var _ = markBlankSideEffect()
+
+func markBlankSideEffect() int {
+	blankSideEffect = blankSideEffect + 1
+	return 0
+}
+
+func blankIdentifierTest1() int {
+	_, r := divmod(7, 4)
+	var _ = r
+	_ = r
+	var x int
+	x, _ = divmod(3, 1)
+	return r + x - 3
+}
+
+func blankIdentifierTest2(_ int, b int) int {
+	_, c := 3, b
+	return c + 5
+}
+
+func redeclarationTest1() int {
+	q, r := divmod(7, 3)
+	p, r := divmod(9, 4)
+	return q*10 + p + r
+}
+
+func redeclarationTest2() int {
+	var x = 1
+	if true {
+		// 外側のスコープの変数は再宣言されず、新しい変数になる
+		x, y := 2, 3
+		x = x + y
+	}
+	x, _, z := 4, 0, 1
+	return x + z
+}
+
+type Celsius int
+type Fahrenheit int
+
+func toFahrenheit(c Celsius) Fahrenheit {
+	return Fahrenheit(c*9/5 + 32)
+}
+
+func namedTypeTest1() int {
+	var c Celsius = 100
+	var f = toFahrenheit(c)
+	return int(f) - 200
+}
+
+func namedTypeTest2() int {
+	var r = 'a' + 2
+	var n = int(r) - 'a'
+	var b = rune(n + 300)
+	return n*100 + int(b)
+}
+
+type Names []string
+
+func namedTypeTest3() int {
+	var names Names = []string{"a", "b"}
+	var xs []string = names
+	return len(xs)
+}
+
+func namedTypeTest4() int {
+	var c Celsius = 5
+	var p = (*Fahrenheit)(&c)
+	*p = 7
+	var names = Names([]string{"a", "b", "c"})
+	var xs = []string(names)
+	return int(c)*10 + len(xs)
+}
+
+func recursiveTypeTest1() int {
+	var a = ListNode{Value: 1}
+	var b = ListNode{Value: 2}
+	var c = ListNode{Value: 3}
+	a.Next = &b
+	b.Next = &c
+	return a.Value + a.Next.Value + a.Next.Next.Value
+}
+
+type ListNode struct {
+	Value int
+	Next  *ListNode
+}
+
+type Person struct {
+	Name string
+	Pet  *Pet
+}
+
+type Pet struct {
+	Age   int
+	Owner *Person
+}
+
+func recursiveTypeTest2() int {
+	var p = Person{Name: "taro"}
+	var q = Pet{Age: 17, Owner: &p}
+	p.Pet = &q
+	return p.Pet.Age + len(p.Pet.Owner.Name)
+}
+
+func forwardTypeTest1() int {
+	var s = Shelf{Books: []Book{Book{Pages: 10}, Book{Pages: 25}}}
+	return s.Books[0].Pages + s.Books[1].Pages
+}
+
+var (
+	multiA, multiB int
+	multiC, multiD = 3, "four"
+	multiQ, multiR = divmod(17, 5)
+)
+
+type (
+	Meter int
+	Pair  struct {
+		Left  int
+		Right int
+	}
+)
+
+func groupedDeclarationTest1() int {
+	multiA = 1
+	return multiA + multiB + multiC + len(multiD) + multiQ*10 + multiR
+}
+
+func groupedDeclarationTest2() int {
+	var (
+		x, y int = 1, 2
+		s        = "abc"
+	)
+	var p, q = divmod(9, 2)
+	var m, n int
+	m, n = 3, -3
+	type Local struct {
+		A int
+	}
+	var l = Local{A: 5}
+	var d Meter = 7
+	var pair = Pair{Left: 10, Right: 20}
+	return x + y + len(s) + p*10 + q + m + n + l.A + int(d) + pair.Right - pair.Left
+}
+
+func groupedDeclarationTest3() int {
+	var x = 10
+	if true {
+		// 初期化式の中のxは外側の変数を指す
+		var x, y = x + 1, x + 2
+		return x + y
+	}
+	return x
+}
+
+type Zeroes struct {
+	N     int
+	S     string
+	Inner Pair
+	Arr   [2]string
+	P     *Pair
+	Xs    []int
+}
+
+var topZero Zeroes
+var topArray [3]int
+var topString string
+
+func zeroValueTest1() int {
+	var z Zeroes
+	var n int
+	var s string
+	var xs []int
+	var arr [2]Pair
+	return n + len(s) + len(xs) + len(z.S) + z.Inner.Left + len(z.Arr[1]) + len(z.Xs) + arr[1].Right + 5
+}
+
+func zeroValueTest2() int {
+	var total = 0
+	for i := 0; i < 3; i = i + 1 {
+		// ループのたびに新しい変数としてゼロ値で初期化される
+		var x int
+		x = x + i
+		total = total + x
+	}
+	var xs []int
+	xs = append(xs, 4)
+	var p = Pair{Right: 3}
+	return total + xs[0] + len(xs) + p.Left + p.Right
+}
+
+func zeroValueTest3() int {
+	topArray[1] = 7
+	return topZero.N + len(topZero.S) + topZero.Inner.Right + len(topZero.Arr[0]) + topArray[0] + topArray[1] + len(topString)
+}
+
+func nilTest1() int {
+	var p *int
+	var xs []int
+	var count = 0
+	if p == nil {
+		count = count + 1
+	}
+	if nil == xs {
+		count = count + 1
+	}
+	var x = 5
+	p = &x
+	if p != nil {
+		count = count + 1
+	}
+	xs = []int{1}
+	if xs == nil {
+		count = count + 10
+	}
+	return count
+}
+
+func nilTest2() int {
+	var xs = []int{1, 2}
+	xs = nil
+	xs = append(xs, 6)
+	var p = &Pair{Left: 1}
+	p = nil
+	if p == nil {
+		return xs[0]
+	}
+	return 0
+}
+
+func newTest1() int {
+	var n = new(int)
+	*n = 5
+	var p = new(Pair)
+	p.Right = 4
+	var s = new(string)
+	var arr = new([2]int)
+	var a = *arr
+	a[1] = 3
+	return *n + p.Left + p.Right + len(*s) + a[0] + a[1]
+}
+
+func compositeLiteralTest1() int {
+	var p = Pair{10, 20}
+	var q = &Pair{Right: 5}
+	var r = *q
+	return p.Left + p.Right + q.Left + r.Right - 5
+}
+
+func compositeLiteralTest2() int {
+	var arr = [3]int{10, 20, 30}
+	var brr = [...]int{1, 2, 3, 4}
+	var crr = [4]int{5}
+	return arr[0] + arr[1] + arr[2] + len(brr) - 4 + crr[0] + crr[3] - 5
+}
+
+func compositeLiteralTest3() int {
+	var pairs = []Pair{{1, 2}, {Left: 3}, Pair{4, 5}}
+	var grid = [2][2]int{{1, 2}, {3, 0}}
+	var ptrs = []*Pair{{Right: 6}}
+	return pairs[0].Left + pairs[0].Right + pairs[1].Left + pairs[2].Right + grid[0][1] + grid[1][0] - 6 + ptrs[0].Right + 5
+}
+
+func compositeLiteralTest4() int {
+	var names = []string{
+		"alpha",
+		"beta",
+	}
+	var p = &[]int{1, 2}
+	return len(names[0]) + len(names[1]) + len(*p) - 2
+}
+
+type Labeled struct {
+	Name  string
+	Point Pair
+	Tags  [2]string
+	Flag  bool
+	Mark  rune
+}
+
+func equalityTest1() int {
+	var count = 0
+	if (Pair{1, 2}) == (Pair{1, 2}) {
+		count = count + 1
+	}
+	var p = Pair{1, 2}
+	var q = Pair{Left: 1}
+	if p != q {
+		count = count + 2
+	}
+	q.Right = 2
+	if p == q {
+		count = count + 4
+	}
+	return count
+}
+
+func equalityTest2() int {
+	var count = 0
+	var a = [3]int{1, 2, 3}
+	var b = [3]int{1, 2, 3}
+	if a == b {
+		count = count + 1
+	}
+	b[2] = 4
+	if a != b {
+		count = count + 2
+	}
+	var grid = [2][2]int{{1, 2}, {3, 4}}
+	if grid == [2][2]int{{1, 2}, {3, 4}} {
+		count = count + 4
+	}
+	var s = "ab"
+	if s+"c" == "abc" {
+		count = count + 8
+	}
+	return count
+}
+
+func equalityTest3() int {
+	var count = 0
+	var x = Labeled{"a" + "b", Pair{1, 2}, [2]string{"x", "y"}, true, 'z'}
+	var y = Labeled{"ab", Pair{1, 2}, [2]string{"x", "y"}, true, 'z'}
+	if x == y {
+		count = count + 1
+	}
+	y.Tags[1] = "w"
+	if x != y {
+		count = count + 2
+	}
+	if "abc" < "abd" && "b" > "abc" {
+		return count
+	}
+	return 0
+}
+
+type Holder struct {
+	Items [2]int
+}
+
+func assignmentCopyTest1() int {
+	var a = Pair{Left: 1, Right: 2}
+	var b = a
+	b.Left = 10
+	var x = Holder{[2]int{3, 4}}
+	var y Holder = x
+	y.Items[0] = 30
+	// 変数を宣言して代入したあとで書き換えても、代入元は変わらない
+	return a.Left*100 + a.Right*10 + x.Items[0]
+}
+
+func literalCopyTest1() int {
+	var p = Pair{Left: 1, Right: 2}
+	var s = []Pair{p}
+	s[0].Right = 7
+	var a = [1]Pair{p}
+	a[0].Right = 8
+	var t = []Pair{}
+	t = append(t, p)
+	t[0].Right = 9
+	var items = [2]int{3, 4}
+	var h = Holder{items}
+	h.Items[0] = 30
+	// 複合リテラルの要素やappendで追加した値を書き換えても、元の値は変わらない
+	return p.Left*100 + p.Right*10 + items[0]
+}
+
+type Number interface {
+	~int | ~rune
+}
+
+type Stack[T any] struct {
+	items []T
+	size  int
+}
+
+type KeyValue[K comparable, V any] struct {
+	Key   K
+	Value V
+}
+
+type List[T any] struct {
+	Value T
+	Next  *List[T]
+}
+
+func Max[T Number](a T, b T) T {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func Sum[T Number](xs ...T) T {
+	var total T
+	for i := 0; i < len(xs); i = i + 1 {
+		total = total + xs[i]
+	}
+	return total
+}
+
+func Index[T comparable](xs []T, x T) int {
+	for i := 0; i < len(xs); i = i + 1 {
+		if xs[i] == x {
+			return i
+		}
+	}
+	return -1
+}
+
+func Push[T any](s *Stack[T], v T) {
+	if s.size < len(s.items) {
+		s.items[s.size] = v
+	} else {
+		s.items = append(s.items, v)
+	}
+	s.size = s.size + 1
+}
+
+func Pop[T any](s *Stack[T]) T {
+	s.size = s.size - 1
+	return s.items[s.size]
+}
+
+func MakeKeyValue[K comparable, V any](k K, v V) KeyValue[K, V] {
+	return KeyValue[K, V]{k, v}
+}
+
+// 本体で別のジェネリック関数を型パラメータのまま呼び出す
+func Max3[T Number](a T, b T, c T) T {
+	return Max(Max(a, b), c)
+}
+
+func Scale[T Number](x T, n int) T {
+	var result T = 0
+	for i := 0; i < n; i = i + 1 {
+		result = result + x
+	}
+	return T(int(result) + 0)
+}
+
+func Length[T any](l *List[T]) int {
+	var n = 0
+	for l != nil {
+		n = n + 1
+		l = l.Next
+	}
+	return n
+}
+
+func genericsTest1() int {
+	var m Meter = 3
+	return Max(3, 7) + int(Max('a', 'c')) + Max[int](1, 2) + int(Max(m, Meter(5)))
+}
+
+func genericsTest2() int {
+	var xs = []int{4, 5}
+	return Sum(1, 2, 3) + Sum(xs...) + Index([]string{"a", "b"}, "b") + Index([]Pair{{1, 2}, {3, 4}}, Pair{3, 4}) + Index(xs, 6) + 1
+}
+
+func genericsTest3() int {
+	var ints Stack[int]
+	Push(&ints, 10)
+	Push(&ints, 20)
+	var words = &Stack[string]{}
+	Push(words, "hello")
+	var top = Pop(&ints)
+	Push(&ints, 3)
+	return top + Pop(&ints) + len(Pop(words))
+}
+
+func genericsTest4() int {
+	var list = &List[int]{1, &List[int]{2, &List[int]{Value: 3}}}
+	var kv = MakeKeyValue("key", 7)
+	var other = KeyValue[string, int]{"key", 7}
+	var count = Length(list) + list.Next.Value + kv.Value
+	if kv == other {
+		count = count + 3
+	}
+	return count
+}
+
+type Distance = Meter
+type IntStack = Stack[int]
+type Later = LaterTarget
+type LaterTarget struct {
+	N int
+}
+
+func addMeters(a Meter, b Meter) Meter {
+	return a + b
+}
+
+func typeAliasTest1() int {
+	var d Distance = 4
+	var m Meter = d
+	var s IntStack
+	Push(&s, 10)
+	var l = Later{N: 7}
+	var t LaterTarget = l
+	return int(addMeters(d, m)) + Pop(&s) - 1 + t.N - 4 + len(s.items) + 1
+}
+
+func typeAliasTest2() int {
+	type Point = struct {
+		X int
+		Y int
+	}
+	type Num = int
+	var p = Point{5, 6}
+	var n Num = p.X
+	var i int = n
+	return i + p.Y + 1
+}
+
+func localTypeTest1() int {
+	var total = 0
+	if true {
+		type Cell struct {
+			V int
+		}
+		var c = Cell{4}
+		total = total + c.V
+	}
+	if true {
+		// 別のブロックでは同じ名前で別の型を宣言できる
+		type Cell struct {
+			A int
+			B int
+		}
+		var c = Cell{2, 3}
+		total = total + c.A + c.B
+	}
+	return total
+}
+
+func Identity[T any](x T) T {
+	return x
+}
+
+// 別々の関数で宣言された同じ名前の型で、別々にインスタンス化される
+func localTypeTest2() int {
+	return localTypeTest2A() + localTypeTest2B()
+}
+
+func localTypeTest2A() int {
+	type T struct {
+		A int
+	}
+	var t = Identity(T{5})
+	return t.A
+}
+
+func localTypeTest2B() int {
+	type T struct {
+		A string
+		B int
+	}
+	var t = Identity(T{"ab", 9})
+	return len(t.A) + t.B
+}
+
+func genericsTest5() int {
+	var m Meter = Max3(Meter(3), Meter(9), Meter(4))
+	return int(m) + Scale(11, 3) + int(Scale('a', 0))
+}
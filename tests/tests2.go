@@ -1,3 +1,93 @@
 package main
 
+import (
+	"github.com/myuu222/myuugo/tests/geometry"
+	m "github.com/myuu222/myuugo/tests/mathutil"
+	. "github.com/myuu222/myuugo/tests/mathutil/digits"
+	"github.com/myuu222/myuugo/tests/platform"
+	_ "github.com/myuu222/myuugo/tests/registrar"
+	"github.com/myuu222/myuugo/tests/textkit"
+)
+
 var test2 int
+
+func init() {
+	initCount = initCount + 100
+}
+
+// tests.goから先に参照される型
+type Shelf struct {
+	Books []Book
+}
+
+type Book struct {
+	Pages int
+}
+
+func importTest1() int {
+	var mathutil = 2
+	return m.Gcd(12, 20) * mathutil
+}
+
+func importTest2() int {
+	return DigitSum(1234) + Count(99) + Base
+}
+
+func importTest3() int {
+	return m.Registered()
+}
+
+func crossPackageTest1() int {
+	var p = geometry.Point{X: 3, Y: -4}
+	var s = geometry.Segment{From: geometry.Origin, To: p}
+	var length geometry.Meter = geometry.Length(s)
+	return int(length + geometry.Meter(1))
+}
+
+func crossPackageTest2() int {
+	var Created = 100
+	geometry.Created = 0
+	var q *geometry.Point = geometry.NewPoint(1, 2)
+	geometry.NewPoint(5, 6)
+	return Created + q.X*10 + q.Y + geometry.Created*1000
+}
+
+func crossPackageTest3() int {
+	var pair = geometry.Pair[int]{First: 4, Second: 5}
+	var points = []geometry.Point{{1, 2}, {3, 4}}
+	return pair.First*pair.Second + points[1].Y + len(points)
+}
+
+func visibilityTest1() int {
+	var c = geometry.Counter{Name: "visits"}
+	geometry.Increment(&c)
+	geometry.Increment(&c)
+	return geometry.Count(c)*10 + len(c.Name)
+}
+
+func buildConstraintTest1() int {
+	return platform.Describe()
+}
+
+func importNameTest1() int {
+	// インポートしたパッケージは、パスの最後の要素ではなくpackage文の名前で参照する
+	textutil.Separator = "+"
+	return len(textutil.Repeat("ab", 3))
+}
+
+func dotImportTypeTest1() int {
+	var d Digits = Split(472)
+	var w = Window[int]{Low: d.Values[0], High: d.Values[2]}
+	var ws = []Window[int]{w}
+	var p *Digits = &d
+	return len(p.Values)*100 + ws[0].Low*10 + ws[0].High
+}
+
+func crossPackageGenericsTest1() int {
+	geometry.Created = 0
+	var far = geometry.Farther(3, -9)
+	var m geometry.Meter = geometry.Farther[geometry.Meter](4, 2)
+	var pair = geometry.MakePair("ab", "cde")
+	var last = Last([]int{5, 6, 7})
+	return -far*1000 + int(m)*100 + len(pair.First+pair.Second)*10 + last - geometry.Created
+}
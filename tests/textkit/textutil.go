@@ -0,0 +1,15 @@
+// ディレクトリの名前とパッケージの名前が異なるパッケージ
+package textutil
+
+var Separator = "-"
+
+func Repeat(s string, n int) string {
+	var result = ""
+	for i := 0; i < n; i = i + 1 {
+		if i > 0 {
+			result = result + Separator
+		}
+		result = result + s
+	}
+	return result
+}